@@ -5,6 +5,7 @@ import (
 
 	"github.com/roessland/curated-axiom-mcp/pkg/axiom"
 	"github.com/roessland/curated-axiom-mcp/pkg/config"
+	"github.com/roessland/curated-axiom-mcp/pkg/utils"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 )
@@ -34,6 +35,10 @@ var configShowCmd = &cobra.Command{
 		// Hide sensitive values
 		displayConfig := *appConfig
 		displayConfig.Axiom.Token = "***HIDDEN***"
+		displayConfig.Profiles = append([]config.AxiomConfig(nil), appConfig.Profiles...)
+		for i := range displayConfig.Profiles {
+			displayConfig.Profiles[i].Token = "***HIDDEN***"
+		}
 
 		data, err := yaml.Marshal(displayConfig)
 		if err != nil {
@@ -51,26 +56,37 @@ var configValidateCmd = &cobra.Command{
 	Long:  "Validate the current configuration and test connection to Axiom.",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		fmt.Println("✓ Configuration is valid")
-		fmt.Printf("✓ Axiom token: %s\n", maskToken(appConfig.Axiom.Token))
-
-		// Test connection to Axiom
-		fmt.Print("Testing connection to Axiom... ")
-		clientConfig := &axiom.AxiomConfig{
-			Token:   appConfig.Axiom.Token,
-			OrgID:   appConfig.Axiom.OrgID,
-			Dataset: appConfig.Axiom.Dataset,
-			URL:     appConfig.Axiom.URL,
+		if appConfig.Axiom.ReplayDir != "" {
+			fmt.Printf("✓ Replaying responses from %s\n", appConfig.Axiom.ReplayDir)
 		}
-		client := axiom.NewClient(clientConfig)
-		
-		// Test connection by trying to get starred queries
-		_, err := client.StarredQueries()
-		if err != nil {
-			fmt.Printf("❌ Failed: %v\n", err)
-			return fmt.Errorf("axiom connection test failed: %w", err)
+
+		// Test connection to Axiom for every profile
+		failed := 0
+		for _, profile := range appConfig.AxiomProfiles() {
+			fmt.Printf("\nProfile %s:\n", profile.Name)
+			fmt.Printf("✓ Axiom token: %s\n", maskToken(profile.Token))
+			if profile.OrgID != "" {
+				fmt.Printf("✓ Organization: %s\n", profile.OrgID)
+			}
+
+			fmt.Print("Testing connection to Axiom... ")
+			client := axiom.NewClient(profile.ClientConfig())
+
+			// Test connection by trying to get starred queries
+			_, err := client.StarredQueries(cmd.Context())
+			if err != nil {
+				axiomErr := utils.ClassifyAxiomError(err)
+				fmt.Printf("❌ Failed (%s): %v\n", axiomErr.Kind, err)
+				fmt.Printf("   %s\n", axiomErr.Hint())
+				failed++
+				continue
+			}
+			fmt.Println("✓ Success")
 		}
-		fmt.Println("✓ Success")
 
+		if failed > 0 {
+			return fmt.Errorf("axiom connection test failed for %d profile(s)", failed)
+		}
 		return nil
 	},
 }
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom"
+	"github.com/roessland/curated-axiom-mcp/pkg/caxiom"
+	"github.com/roessland/curated-axiom-mcp/pkg/querylint"
+	"github.com/spf13/cobra"
+)
+
+var (
+	lintDir     string
+	lintProfile string
+	lintFormat  string
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Check curated queries for problems",
+	Long: `Check the CuratedAxiomMCP starred queries in Axiom, or the .apl files in a
+directory with --dir, and report every problem per query: queries that don't
+load, params without a placeholder, placeholders without a param, unannotated
+declared parameters, duplicate or invalid tool names, missing descriptions, and
+queries without a time filter or limit. With several profiles, tool names are
+checked with the profile prefix the server adds. Starred queries excluded by the
+profile's filter are skipped, like the server does. Exits with a non-zero
+status if there are problems.`,
+	// Problems are reported as an error, which is not a usage problem
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if lintFormat != "text" && lintFormat != "json" {
+			return fmt.Errorf("unknown format %q, use text or json", lintFormat)
+		}
+
+		var queries []querylint.Query
+		if lintDir != "" {
+			var err error
+			queries, err = querylint.ReadDir(lintDir)
+			if err != nil {
+				return err
+			}
+		} else {
+			profile, err := selectProfile(lintProfile)
+			if err != nil {
+				return err
+			}
+			client := axiom.NewClient(profile.ClientConfig())
+			starred, err := client.StarredQueries(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("failed to fetch starred queries: %w", err)
+			}
+			for _, sq := range starred {
+				// Also lint queries with a malformed marker, which are not loaded
+				if !strings.Contains(sq.Query.APL, "CuratedAxiomMCP") {
+					continue
+				}
+				// Skip queries the profile's filter keeps from being loaded
+				var toolName string
+				if parsed, err := caxiom.ParseStarredQuery(sq.Name, sq.Query.APL); err == nil {
+					toolName = parsed.Metadata.CuratedAxiomMCP.ToolName
+				}
+				if included, _ := profile.Filter.MatchStarredQuery(sq, toolName); !included {
+					continue
+				}
+				queries = append(queries, querylint.Query{Name: sq.Name, APL: sq.Query.APL})
+			}
+		}
+
+		// Lint the tool names the server publishes, which are prefixed with the
+		// profile name when several profiles are configured
+		if len(appConfig.AxiomProfiles()) > 1 {
+			profile, err := selectProfile(lintProfile)
+			if err != nil {
+				return err
+			}
+			for i := range queries {
+				queries[i].Profile = profile.Name
+			}
+		}
+
+		results := querylint.Lint(queries)
+		problems := querylint.ProblemCount(results)
+		if lintFormat == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(map[string]any{"queries": results, "problems": problems}); err != nil {
+				return err
+			}
+		} else {
+			printLintResults(results)
+		}
+
+		if problems > 0 {
+			return fmt.Errorf("%d problem(s) in %d curated queries", problems, len(results))
+		}
+		if lintFormat == "text" {
+			fmt.Printf("✓ %d curated queries, no problems\n", len(results))
+		}
+		return nil
+	},
+}
+
+// printLintResults prints one line per problem, prefixed by the file or
+// starred query name and the line if known
+func printLintResults(results []querylint.Result) {
+	for _, result := range results {
+		source := result.Path
+		if source == "" {
+			source = result.Name
+		}
+		for _, problem := range result.Problems {
+			if problem.Line > 0 {
+				fmt.Printf("%s:%d: %s (%s)\n", source, problem.Line, problem.Message, problem.Rule)
+			} else {
+				fmt.Printf("%s: %s (%s)\n", source, problem.Message, problem.Rule)
+			}
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+
+	lintCmd.Flags().StringVar(&lintDir, "dir", "",
+		"lint the .apl files in this directory instead of the starred queries in Axiom")
+	lintCmd.Flags().StringVar(&lintProfile, "profile", "",
+		"Axiom profile to lint (default: the first profile)")
+	lintCmd.Flags().StringVar(&lintFormat, "format", "text",
+		"output format, text or json")
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom"
+	"github.com/roessland/curated-axiom-mcp/pkg/caxiom"
+	"github.com/roessland/curated-axiom-mcp/pkg/config"
+	"github.com/roessland/curated-axiom-mcp/pkg/querysync"
+	"github.com/spf13/cobra"
+)
+
+var (
+	queriesDir     string
+	queriesProfile string
+	queriesPrune   bool
+	queriesDryRun  bool
+)
+
+var queriesCmd = &cobra.Command{
+	Use:   "queries",
+	Short: "Sync curated queries with a directory",
+	Long: `Mirror the CuratedAxiomMCP starred queries in Axiom to and from a directory
+of .apl files, so changes to curated queries can go through code review.`,
+}
+
+var queriesPullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "Write curated starred queries to the directory",
+	Long: `Write every CuratedAxiomMCP starred query in Axiom to an .apl file in the
+directory. Use --prune to remove files of queries that no longer exist in Axiom.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := queriesClient()
+		if err != nil {
+			return err
+		}
+
+		changes, err := querysync.Pull(cmd.Context(), client, queriesDir, querysync.Options{
+			Prune:  queriesPrune,
+			DryRun: queriesDryRun,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to pull queries: %w", err)
+		}
+
+		pulled := 0
+		for _, change := range changes {
+			switch {
+			case change.Kind == querysync.Delete:
+				fmt.Printf("+ %s (%s created)\n", change.Name, change.Local.Path)
+			case change.Kind == querysync.Update:
+				fmt.Printf("~ %s (%s updated)\n", change.Name, change.Local.Path)
+			case queriesPrune:
+				fmt.Printf("- %s (%s removed)\n", change.Name, change.Local.Path)
+			default:
+				fmt.Printf("  %s (%s is not in Axiom, use --prune to remove it)\n", change.Name, change.Local.Path)
+				continue
+			}
+			pulled++
+		}
+		printSyncSummary(pulled, "file")
+		return nil
+	},
+}
+
+var queriesPushCmd = &cobra.Command{
+	Use:   "push",
+	Short: "Create and update curated starred queries from the directory",
+	Long: `Create and update starred queries in Axiom so they match the .apl files in the
+directory. Use --prune to delete curated starred queries without a file.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := queriesClient()
+		if err != nil {
+			return err
+		}
+
+		changes, err := querysync.Push(cmd.Context(), client, queriesDir, querysync.Options{
+			Prune:  queriesPrune,
+			DryRun: queriesDryRun,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to push queries: %w", err)
+		}
+
+		pushed := 0
+		for _, change := range changes {
+			switch {
+			case change.Kind == querysync.Create:
+				fmt.Printf("+ %s (created)\n", change.Name)
+			case change.Kind == querysync.Update:
+				fmt.Printf("~ %s (updated)\n", change.Name)
+			case queriesPrune:
+				fmt.Printf("- %s (deleted)\n", change.Name)
+			default:
+				fmt.Printf("  %s (has no file, use --prune to delete it)\n", change.Name)
+				continue
+			}
+			pushed++
+		}
+		printSyncSummary(pushed, "starred query")
+		return nil
+	},
+}
+
+var queriesDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show drift between the directory and Axiom",
+	Long: `Show the differences between the .apl files in the directory and the curated
+starred queries in Axiom. Exits with a non-zero status if they differ.`,
+	// Drift is reported as an error, which is not a usage problem
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := queriesClient()
+		if err != nil {
+			return err
+		}
+
+		changes, err := querysync.DiffDir(cmd.Context(), client, queriesDir)
+		if err != nil {
+			return fmt.Errorf("failed to diff queries: %w", err)
+		}
+		if len(changes) == 0 {
+			fmt.Println("✓ No drift")
+			return nil
+		}
+
+		for _, change := range changes {
+			switch change.Kind {
+			case querysync.Create:
+				fmt.Printf("Only in %s: %s\n", queriesDir, change.Local.Path)
+			case querysync.Delete:
+				fmt.Printf("Only in Axiom: %s\n", change.Name)
+			case querysync.Update:
+				fmt.Printf("--- Axiom: %s\n+++ %s\n", change.Name, change.Local.Path)
+				fmt.Print(querysync.Diff(strings.TrimRight(change.Remote.Query.APL, " \t\r\n"), change.Local.APL))
+			}
+		}
+		return fmt.Errorf("%d curated queries differ between %s and Axiom", len(changes), queriesDir)
+	},
+}
+
+var queriesSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of the CuratedAxiomMCP metadata",
+	Long: `Print the JSON Schema of the CuratedAxiomMCP metadata, for validating metadata
+in editors. The schema describes the metadata without the comment prefix.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, err := os.Stdout.Write(caxiom.MetadataSchema)
+		return err
+	},
+}
+
+// queriesClient returns a client for the profile selected with --profile
+func queriesClient() (*axiom.Client, error) {
+	return profileClient(queriesProfile)
+}
+
+// profileClient returns a client for the named profile, or the first profile
+// if the name is empty
+func profileClient(name string) (*axiom.Client, error) {
+	profile, err := selectProfile(name)
+	if err != nil {
+		return nil, err
+	}
+	return axiom.NewClient(profile.ClientConfig()), nil
+}
+
+// selectProfile returns the named profile, or the first profile if the name
+// is empty
+func selectProfile(name string) (config.AxiomConfig, error) {
+	profiles := appConfig.AxiomProfiles()
+	if name == "" {
+		return profiles[0], nil
+	}
+
+	names := make([]string, 0, len(profiles))
+	for _, profile := range profiles {
+		if profile.Name == name {
+			return profile, nil
+		}
+		names = append(names, profile.Name)
+	}
+	return config.AxiomConfig{}, fmt.Errorf("unknown profile %q, available profiles: %s", name, strings.Join(names, ", "))
+}
+
+func printSyncSummary(count int, noun string) {
+	if queriesDryRun {
+		fmt.Printf("Dry run: %d %s(s) would change\n", count, noun)
+		return
+	}
+	fmt.Printf("✓ %d %s(s) changed\n", count, noun)
+}
+
+func init() {
+	rootCmd.AddCommand(queriesCmd)
+	queriesCmd.AddCommand(queriesPullCmd)
+	queriesCmd.AddCommand(queriesPushCmd)
+	queriesCmd.AddCommand(queriesDiffCmd)
+	queriesCmd.AddCommand(queriesSchemaCmd)
+
+	queriesCmd.PersistentFlags().StringVar(&queriesDir, "dir", "queries",
+		"directory of curated query .apl files")
+	queriesCmd.PersistentFlags().StringVar(&queriesProfile, "profile", "",
+		"Axiom profile to sync (default: the first profile)")
+	for _, cmd := range []*cobra.Command{queriesPullCmd, queriesPushCmd} {
+		cmd.Flags().BoolVar(&queriesPrune, "prune", false,
+			"also remove queries that only exist on the other side")
+		cmd.Flags().BoolVar(&queriesDryRun, "dry-run", false,
+			"only show what would change")
+	}
+}
@@ -16,6 +16,8 @@ import (
 var (
 	cfgFile     string
 	portFlag    int
+	recordDir   string
+	replayDir   string
 	queriesFile string
 	appConfig   *config.AppConfig
 	registry    *config.Registry
@@ -31,9 +33,17 @@ whitelisted Axiom queries with simplified results.`,
 		if cmd.Name() == "init" && cmd.Parent() != nil && cmd.Parent().Name() == "config" {
 			return nil
 		}
+		// The metadata schema and linting files don't need a config either
+		if cmd == queriesSchemaCmd || (cmd == lintCmd && lintDir != "") {
+			return nil
+		}
 
 		var err error
-		appConfig, err = config.LoadConfig(cfgFile, portFlag)
+		appConfig, err = config.LoadConfig(cfgFile, config.Flags{
+			Port:      portFlag,
+			RecordDir: recordDir,
+			ReplayDir: replayDir,
+		})
 		if err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
@@ -45,7 +55,7 @@ whitelisted Axiom queries with simplified results.`,
 		utils.SetupLogger(&appConfig.Logging, stdio)
 
 		// Initialize query registry with Axiom client for dynamic loading
-		registry = config.NewRegistryWithAxiom(&appConfig.Axiom, appConfig.Queries.CacheTTL)
+		registry = config.NewRegistryWithProfiles(appConfig.AxiomProfiles(), appConfig.Queries.CacheTTL)
 
 		return nil
 	},
@@ -90,6 +100,10 @@ func init() {
 		"server port (overrides config file, but not PORT env var)")
 	rootCmd.PersistentFlags().StringVar(&queriesFile, "queries", "",
 		"queries file (default: ~/.config/curated-axiom-mcp/queries.yaml)")
+	rootCmd.PersistentFlags().StringVar(&recordDir, "record", "",
+		"record Axiom responses as cassettes in this directory")
+	rootCmd.PersistentFlags().StringVar(&replayDir, "replay", "",
+		"serve Axiom responses from cassettes in this directory instead of calling Axiom")
 
 	rootCmd.Flags().Bool("stdio", false, "run as stdio MCP server")
 }
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/roessland/curated-axiom-mcp/pkg/cserver"
+	"github.com/spf13/cobra"
+)
+
+var testCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Run the examples of curated tools",
+	Long: `Render and run the Examples in the CuratedAxiomMCP metadata of every curated
+tool, and check the row counts and columns of the results. Use --replay to run
+the examples against recorded responses instead of Axiom, and --record to
+record them. Relative times are replayed as of the time of recording. Exits
+with a non-zero status if an example fails.`,
+	// Failed examples are reported as an error, which is not a usage problem
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mcpManager := cserver.NewMCP(appConfig, registry)
+		results, err := mcpManager.TestExamples(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			fmt.Println("No examples found, add Examples to the CuratedAxiomMCP metadata")
+			return nil
+		}
+
+		failed := 0
+		for _, result := range results {
+			name := fmt.Sprintf("%s example %d", result.ToolName, result.Number)
+			if result.Example.Description != "" {
+				name += fmt.Sprintf(" (%s)", result.Example.Description)
+			}
+			if result.Err != nil {
+				fmt.Printf("❌ %s: %v\n", name, result.Err)
+				failed++
+				continue
+			}
+			fmt.Printf("✓ %s: %d rows\n", name, result.Rows)
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d of %d examples failed", failed, len(results))
+		}
+		fmt.Printf("✓ %d examples passed\n", len(results))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(testCmd)
+}
@@ -0,0 +1,103 @@
+package axiomtest
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom"
+)
+
+//go:embed testdata/default
+var defaultFixtures embed.FS
+
+// DefaultFixtures returns the built-in fixture set. It contains the
+// entity_data curated query from the README, results for it and the
+// datasets it queries.
+func DefaultFixtures() fs.FS {
+	sub, err := fs.Sub(defaultFixtures, "testdata/default")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+// resultFixture is the on-disk format of a query result fixture
+type resultFixture struct {
+	APL         string        `json:"apl,omitempty"`          // Serve for queries exactly matching this APL
+	APLContains string        `json:"apl_contains,omitempty"` // Serve for queries containing this substring
+	ElapsedMs   int           `json:"elapsed_ms,omitempty"`
+	Fields      []query.Field `json:"fields"`
+	Rows        [][]any       `json:"rows"`
+}
+
+// LoadFixtures loads starred queries and query results from a fixture directory.
+//
+// The directory may contain a starred_queries.json file with a JSON array of
+// starred queries, a datasets.json file with a JSON array of datasets, and a
+// results directory with one JSON file per result.
+// A result without apl or apl_contains becomes the default result.
+func (s *Server) LoadFixtures(fsys fs.FS) error {
+	data, err := fs.ReadFile(fsys, "starred_queries.json")
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("failed to read starred queries fixture: %w", err)
+	default:
+		var queries []axiom.StarredQuery
+		if err := json.Unmarshal(data, &queries); err != nil {
+			return fmt.Errorf("failed to parse starred queries fixture: %w", err)
+		}
+		s.SetStarredQueries(queries)
+	}
+
+	data, err = fs.ReadFile(fsys, "datasets.json")
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("failed to read datasets fixture: %w", err)
+	default:
+		var datasets []axiom.Dataset
+		if err := json.Unmarshal(data, &datasets); err != nil {
+			return fmt.Errorf("failed to parse datasets fixture: %w", err)
+		}
+		s.SetDatasets(datasets)
+	}
+
+	files, err := fs.Glob(fsys, "results/*.json")
+	if err != nil {
+		return fmt.Errorf("failed to list result fixtures: %w", err)
+	}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return fmt.Errorf("failed to read result fixture %s: %w", file, err)
+		}
+
+		var fixture resultFixture
+		if err := json.Unmarshal(data, &fixture); err != nil {
+			return fmt.Errorf("failed to parse result fixture %s: %w", path.Base(file), err)
+		}
+
+		result := Result{
+			Fields:      fixture.Fields,
+			Rows:        fixture.Rows,
+			ElapsedTime: time.Duration(fixture.ElapsedMs) * time.Millisecond,
+		}
+		switch {
+		case fixture.APL != "":
+			s.SetResult(fixture.APL, result)
+		case fixture.APLContains != "":
+			s.SetResultContaining(fixture.APLContains, result)
+		default:
+			s.SetDefaultResult(result)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,534 @@
+// Package axiomtest provides an in-process fake of the Axiom API for tests.
+//
+// The fake server implements the starred queries (including create, update
+// and delete), datasets and APL query endpoints used by axiom.Client.
+// Starred queries and tabular query results can be loaded from fixtures, and
+// errors such as 401, 429, 5xx and slow responses can be injected to exercise
+// error handling without a live Axiom organization.
+package axiomtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom"
+)
+
+const (
+	// Token is the API token accepted by the fake server
+	Token = "xaat-axiomtest-token"
+
+	// StarredQueriesPath is the path of the starred queries endpoint
+	StarredQueriesPath = "/v2/apl-starred-queries"
+
+	// DatasetsPath is the path of the datasets endpoint
+	DatasetsPath = "/v2/datasets"
+
+	// QueryPath is the path of the APL query endpoint
+	QueryPath = "/v1/datasets/_apl"
+)
+
+// Result is a tabular query result served by the fake server
+type Result struct {
+	Fields      []query.Field      `json:"fields"`
+	Rows        [][]any            `json:"rows"`
+	ElapsedTime time.Duration      `json:"-"`
+	Details     axiom.QueryDetails `json:"-"` // Status details such as blocks examined and partial result flags
+}
+
+// Fault is an error or delay injected into responses of an endpoint
+type Fault struct {
+	Status     int           // HTTP status code to respond with, zero means respond normally after Delay
+	Message    string        // Error message in the JSON error body
+	RetryAfter time.Duration // Sets rate limit headers when Status is 429
+	Delay      time.Duration // Delay before responding, aborted if the client goes away
+	Times      int           // Number of requests the fault applies to, zero means once
+
+	// Committed makes the server process the request before responding with
+	// Status, like a server that fails after storing a change
+	Committed bool
+}
+
+// Request records a request received by the fake server
+type Request struct {
+	Method string
+	Path   string
+	Query  string
+	APL    string
+}
+
+type resultMatcher struct {
+	apl      string
+	contains bool
+	result   Result
+}
+
+// Server is a fake Axiom API server
+type Server struct {
+	*httptest.Server
+
+	mu             sync.Mutex
+	starredQueries []axiom.StarredQuery
+	nextQueryID    int
+	datasets       []axiom.Dataset
+	results        []resultMatcher
+	defaultResult  *Result
+	faults         map[string][]Fault
+	requests       []Request
+	activeQueries  int
+	peakQueries    int
+}
+
+// NewServer starts a fake Axiom API server which is closed when the test ends
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	s := &Server{
+		faults: make(map[string][]Fault),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(StarredQueriesPath, s.handleStarredQueries)
+	mux.HandleFunc(StarredQueriesPath+"/{id}", s.handleStarredQuery)
+	mux.HandleFunc(DatasetsPath, s.handleDatasets)
+	mux.HandleFunc(QueryPath, s.handleQuery)
+	s.Server = httptest.NewServer(s.authenticate(s.commitFaults(mux)))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// Config returns an AxiomConfig pointing at the fake server
+func (s *Server) Config() *axiom.AxiomConfig {
+	return &axiom.AxiomConfig{
+		Token: Token,
+		URL:   s.URL,
+	}
+}
+
+// Client returns an Axiom client connected to the fake server
+func (s *Server) Client() *axiom.Client {
+	return axiom.NewClient(s.Config())
+}
+
+// AddStarredQuery adds a starred query with the given name and APL
+func (s *Server) AddStarredQuery(name, apl string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.starredQueries = append(s.starredQueries, axiom.StarredQuery{
+		ID:    fmt.Sprintf("sq-%d", len(s.starredQueries)+1),
+		Name:  name,
+		Kind:  "apl",
+		Who:   "axiomtest",
+		Query: axiom.StarredQueryContent{APL: apl},
+	})
+}
+
+// SetStarredQueries replaces all starred queries
+func (s *Server) SetStarredQueries(queries []axiom.StarredQuery) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.starredQueries = queries
+}
+
+// AddDataset adds a dataset with the given name and description
+func (s *Server) AddDataset(name, description string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.datasets = append(s.datasets, axiom.Dataset{
+		ID:          name,
+		Name:        name,
+		Description: description,
+		Who:         "axiomtest",
+		Created:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+}
+
+// SetDatasets replaces all datasets
+func (s *Server) SetDatasets(datasets []axiom.Dataset) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.datasets = datasets
+}
+
+// SetResult sets the result returned for queries exactly matching apl
+func (s *Server) SetResult(apl string, result Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results = append(s.results, resultMatcher{apl: strings.TrimSpace(apl), result: result})
+}
+
+// SetResultContaining sets the result returned for queries containing substr
+func (s *Server) SetResultContaining(substr string, result Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results = append(s.results, resultMatcher{apl: substr, contains: true, result: result})
+}
+
+// SetDefaultResult sets the result returned for queries without a matching result
+func (s *Server) SetDefaultResult(result Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaultResult = &result
+}
+
+// InjectFault makes the next request(s) to path fail or respond slowly
+func (s *Server) InjectFault(path string, fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if fault.Times <= 0 {
+		fault.Times = 1
+	}
+	s.faults[path] = append(s.faults[path], fault)
+}
+
+// Requests returns all requests received so far
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// QueryCount returns the number of APL queries received so far
+func (s *Server) QueryCount() int {
+	count := 0
+	for _, req := range s.Requests() {
+		if req.Path == QueryPath {
+			count++
+		}
+	}
+	return count
+}
+
+// PeakConcurrentQueries returns the highest number of APL queries that were
+// being processed at the same time
+func (s *Server) PeakConcurrentQueries() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.peakQueries
+}
+
+// authenticate rejects requests without the fake server's token
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+Token {
+			writeError(w, http.StatusUnauthorized, "invalid authentication credentials")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleStarredQueries(w http.ResponseWriter, r *http.Request) {
+	s.record(r, "")
+	if s.applyFault(w, r) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		queries := append([]axiom.StarredQuery{}, s.starredQueries...)
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, queries)
+	case http.MethodPost:
+		var sq axiom.StarredQuery
+		if err := json.NewDecoder(r.Body).Decode(&sq); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+
+		s.mu.Lock()
+		s.nextQueryID++
+		sq.ID = fmt.Sprintf("sq-new-%d", s.nextQueryID)
+		s.starredQueries = append(s.starredQueries, sq)
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, sq)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleStarredQuery updates or deletes a single starred query
+func (s *Server) handleStarredQuery(w http.ResponseWriter, r *http.Request) {
+	s.record(r, "")
+	if s.applyFault(w, r) {
+		return
+	}
+
+	id := r.PathValue("id")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := -1
+	for i, sq := range s.starredQueries {
+		if sq.ID == id {
+			index = i
+		}
+	}
+	if index < 0 {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("starred query %s not found", id))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var sq axiom.StarredQuery
+		if err := json.NewDecoder(r.Body).Decode(&sq); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		sq.ID = id
+		s.starredQueries[index] = sq
+		writeJSON(w, http.StatusOK, sq)
+	case http.MethodDelete:
+		s.starredQueries = append(s.starredQueries[:index:index], s.starredQueries[index+1:]...)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// StarredQueries returns the current starred queries, including changes made
+// through the API
+func (s *Server) StarredQueries() []axiom.StarredQuery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]axiom.StarredQuery(nil), s.starredQueries...)
+}
+
+func (s *Server) handleDatasets(w http.ResponseWriter, r *http.Request) {
+	s.record(r, "")
+	if s.applyFault(w, r) {
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	s.mu.Lock()
+	datasets := append([]axiom.Dataset{}, s.datasets...)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, datasets)
+}
+
+func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var body struct {
+		APL string `json:"apl"`
+	}
+	data, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(data, &body)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	s.record(r, body.APL)
+	s.mu.Lock()
+	s.activeQueries++
+	s.peakQueries = max(s.peakQueries, s.activeQueries)
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.activeQueries--
+		s.mu.Unlock()
+	}()
+
+	if s.applyFault(w, r) {
+		return
+	}
+
+	result, ok := s.lookupResult(body.APL)
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("axiomtest: no result configured for query %q", body.APL))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tabularResponse(result))
+}
+
+func (s *Server) record(r *http.Request, apl string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.RawQuery,
+		APL:    apl,
+	})
+}
+
+func (s *Server) lookupResult(apl string) (Result, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	trimmed := strings.TrimSpace(apl)
+	for _, m := range s.results {
+		if (m.contains && strings.Contains(apl, m.apl)) || (!m.contains && trimmed == m.apl) {
+			return m.result, true
+		}
+	}
+	if s.defaultResult != nil {
+		return *s.defaultResult, true
+	}
+	return Result{}, false
+}
+
+// commitFaults serves requests whose next pending fault is Committed, and
+// then responds with the fault instead of the response
+func (s *Server) commitFaults(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		pending := s.faults[r.URL.Path]
+		if len(pending) == 0 || !pending[0].Committed {
+			s.mu.Unlock()
+			next.ServeHTTP(w, r)
+			return
+		}
+		fault := pending[0]
+		pending[0].Times--
+		if pending[0].Times <= 0 {
+			s.faults[r.URL.Path] = pending[1:]
+		}
+		s.mu.Unlock()
+
+		next.ServeHTTP(httptest.NewRecorder(), r)
+		message := fault.Message
+		if message == "" {
+			message = http.StatusText(fault.Status)
+		}
+		writeError(w, fault.Status, message)
+	})
+}
+
+// applyFault writes the next pending fault for the request path, if any.
+// It returns true if the response has been written.
+func (s *Server) applyFault(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	pending := s.faults[r.URL.Path]
+	if len(pending) == 0 {
+		s.mu.Unlock()
+		return false
+	}
+	fault := pending[0]
+	pending[0].Times--
+	if pending[0].Times <= 0 {
+		s.faults[r.URL.Path] = pending[1:]
+	}
+	s.mu.Unlock()
+
+	if fault.Delay > 0 {
+		select {
+		case <-time.After(fault.Delay):
+		case <-r.Context().Done():
+			return true
+		}
+	}
+
+	if fault.Status == 0 {
+		return false
+	}
+
+	if fault.Status == http.StatusTooManyRequests {
+		retryAfter := fault.RetryAfter
+		if retryAfter <= 0 {
+			retryAfter = time.Second
+		}
+		reset := time.Now().Add(retryAfter)
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+		w.Header().Set("X-QueryLimit-Limit", "100")
+		w.Header().Set("X-QueryLimit-Remaining", "0")
+		w.Header().Set("X-QueryLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	}
+
+	message := fault.Message
+	if message == "" {
+		message = http.StatusText(fault.Status)
+	}
+	writeError(w, fault.Status, message)
+	return true
+}
+
+// tabularResponse converts a Result into the Axiom tabular wire format
+func tabularResponse(result Result) map[string]any {
+	columns := make([][]any, len(result.Fields))
+	for i := range columns {
+		columns[i] = make([]any, 0, len(result.Rows))
+	}
+	for _, row := range result.Rows {
+		for i := range columns {
+			var value any
+			if i < len(row) {
+				value = row[i]
+			}
+			columns[i] = append(columns[i], value)
+		}
+	}
+
+	fields := result.Fields
+	if fields == nil {
+		fields = []query.Field{}
+	}
+	messages := result.Details.Messages
+	if messages == nil {
+		messages = []axiom.QueryMessage{}
+	}
+
+	return map[string]any{
+		"format": "tabular",
+		"status": map[string]any{
+			// Axiom reports the elapsed time in microseconds
+			"elapsedTime":    result.ElapsedTime.Microseconds(),
+			"rowsExamined":   len(result.Rows),
+			"rowsMatched":    len(result.Rows),
+			"blocksExamined": result.Details.BlocksExamined,
+			"blocksCached":   result.Details.BlocksCached,
+			"blocksMatched":  result.Details.BlocksMatched,
+			"bytesScanned":   result.Details.BytesScanned,
+			"numGroups":      result.Details.NumGroups,
+			"isPartial":      result.Details.IsPartial,
+			"isEstimate":     result.Details.IsEstimate,
+			"messages":       messages,
+		},
+		"tables": []map[string]any{{
+			"name":    "0",
+			"sources": []map[string]any{},
+			"fields":  fields,
+			"order":   []any{},
+			"groups":  []any{},
+			"columns": columns,
+		}},
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message})
+}
@@ -0,0 +1,257 @@
+// Package cassette records Axiom API responses to disk and replays them.
+//
+// Each request/response pair is stored as a JSON cassette file. APL queries
+// are keyed by the rendered APL, so replaying a session returns the exact
+// responses the agent saw while recording, without an Axiom token or network.
+package cassette
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// queryPath is the path of the APL query endpoint
+const queryPath = "/v1/datasets/_apl"
+
+// Cassette is a recorded request/response pair
+type Cassette struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest identifies the request a cassette was recorded for
+type RecordedRequest struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Query  string `json:"query,omitempty"`
+	APL    string `json:"apl,omitempty"`
+}
+
+// RecordedResponse is the response replayed for a cassette
+type RecordedResponse struct {
+	StatusCode int               `json:"status_code"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body"`
+}
+
+// Transport is an http.RoundTripper that records or replays cassettes
+type Transport struct {
+	dir  string
+	next http.RoundTripper // nil when replaying
+}
+
+// NewRecorder returns a transport that sends requests using next and saves
+// every response as a cassette in dir
+func NewRecorder(dir string, next http.RoundTripper) *Transport {
+	return &Transport{dir: dir, next: next}
+}
+
+// NewReplayer returns a transport that serves responses from the cassettes
+// in dir and never touches the network
+func NewReplayer(dir string) *Transport {
+	return &Transport{dir: dir}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := describeRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(t.dir, Key(recorded)+".json")
+
+	if t.next == nil {
+		return t.replay(req, recorded, path)
+	}
+	return t.record(req, recorded, path)
+}
+
+func (t *Transport) replay(req *http.Request, recorded RecordedRequest, path string) (*http.Response, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if recorded.APL != "" {
+			return nil, fmt.Errorf("no cassette recorded for query %q in %s", recorded.APL, t.dir)
+		}
+		return nil, fmt.Errorf("no cassette recorded for %s %s in %s", recorded.Method, recorded.Path, t.dir)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+
+	resp := &http.Response{
+		StatusCode:    cassette.Response.StatusCode,
+		Status:        fmt.Sprintf("%d %s", cassette.Response.StatusCode, http.StatusText(cassette.Response.StatusCode)),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header),
+		Body:          io.NopCloser(strings.NewReader(cassette.Response.Body)),
+		ContentLength: int64(len(cassette.Response.Body)),
+		Request:       req,
+	}
+	for name, value := range cassette.Response.Headers {
+		resp.Header.Set(name, value)
+	}
+	return resp, nil
+}
+
+func (t *Transport) record(req *http.Request, recorded RecordedRequest, path string) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	cassette := Cassette{
+		Request: recorded,
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Headers:    recordedHeaders(resp.Header),
+			Body:       string(body),
+		},
+	}
+	if err := save(path, cassette); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Key returns the cassette key of a request. APL queries are keyed by the
+// rendered APL, other requests by method, path and query string.
+func Key(req RecordedRequest) string {
+	var prefix, identity string
+	if req.APL != "" {
+		prefix = "query"
+		identity = req.APL
+	} else {
+		prefix = strings.ToLower(req.Method) + strings.ReplaceAll(req.Path, "/", "_")
+		identity = req.Method + " " + req.Path + "?" + req.Query
+	}
+
+	sum := sha256.Sum256([]byte(identity))
+	return prefix + "-" + hex.EncodeToString(sum[:8])
+}
+
+// describeRequest extracts the identifying parts of a request, reading the
+// APL from the body of query requests
+func describeRequest(req *http.Request) (RecordedRequest, error) {
+	recorded := RecordedRequest{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  req.URL.RawQuery,
+	}
+
+	if req.URL.Path != queryPath || req.Body == nil {
+		return recorded, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return recorded, fmt.Errorf("failed to read request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	var queryRequest struct {
+		APL string `json:"apl"`
+	}
+	if err := json.Unmarshal(body, &queryRequest); err != nil {
+		return recorded, fmt.Errorf("failed to parse query request: %w", err)
+	}
+	recorded.APL = queryRequest.APL
+
+	return recorded, nil
+}
+
+// recordedHeaders keeps the response headers needed to replay a response
+func recordedHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string)
+	for name := range header {
+		canonical := http.CanonicalHeaderKey(name)
+		if canonical == "Content-Type" || canonical == "Retry-After" || strings.HasPrefix(canonical, "X-") {
+			headers[canonical] = header.Get(name)
+		}
+	}
+	return headers
+}
+
+// save writes a cassette atomically, so concurrent recordings of the same
+// request never leave a partial file behind
+func save(path string, cassette Cassette) error {
+	data, err := json.MarshalIndent(cassette, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cassette: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".cassette-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cassette file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// clockFile stores the time a session was recorded at
+const clockFile = "clock.json"
+
+// SaveClock stores the time a session is recorded at in dir, so replays can
+// resolve relative times the same way and render the same APL
+func SaveClock(dir string, now time.Time) error {
+	data, err := json.Marshal(map[string]time.Time{"now": now.UTC()})
+	if err != nil {
+		return fmt.Errorf("failed to marshal clock: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+	return os.WriteFile(filepath.Join(dir, clockFile), data, 0644)
+}
+
+// LoadClock returns the time stored by SaveClock, or false if dir has none
+func LoadClock(dir string) (time.Time, bool, error) {
+	data, err := os.ReadFile(filepath.Join(dir, clockFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, false, nil
+	} else if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to read clock: %w", err)
+	}
+	var clock map[string]time.Time
+	if err := json.Unmarshal(data, &clock); err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to parse clock %s: %w", clockFile, err)
+	}
+	return clock["now"], true, nil
+}
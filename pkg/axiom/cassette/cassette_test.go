@@ -0,0 +1,66 @@
+package cassette_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom"
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom/axiomtest"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	srv := axiomtest.NewServer(t)
+	if err := srv.LoadFixtures(axiomtest.DefaultFixtures()); err != nil {
+		t.Fatalf("Failed to load fixtures: %v", err)
+	}
+
+	// Record a session against the fake server
+	recordConfig := srv.Config()
+	recordConfig.RecordDir = dir
+	recorder := axiom.NewClient(recordConfig)
+
+	ctx := context.Background()
+	if _, err := recorder.StarredQueries(ctx); err != nil {
+		t.Fatalf("Failed to record starred queries: %v", err)
+	}
+	if _, err := recorder.ExecuteQuery(ctx, "['events'] | limit 10"); err != nil {
+		t.Fatalf("Failed to record query: %v", err)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read cassette directory: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 cassettes, got %d", len(files))
+	}
+
+	// Replay without a token or server
+	srv.Close()
+	replayer := axiom.NewClient(&axiom.AxiomConfig{ReplayDir: dir})
+
+	queries, err := replayer.StarredQueries(ctx)
+	if err != nil {
+		t.Fatalf("Failed to replay starred queries: %v", err)
+	}
+	if len(queries) != 2 {
+		t.Errorf("Expected 2 replayed starred queries, got %d", len(queries))
+	}
+
+	result, err := replayer.ExecuteQuery(ctx, "['events'] | limit 10")
+	if err != nil {
+		t.Fatalf("Failed to replay query: %v", err)
+	}
+	if got := len(result.Tables[0].Columns[0]); got != 4 {
+		t.Errorf("Expected 4 replayed rows, got %d", got)
+	}
+
+	_, err = replayer.ExecuteQuery(ctx, "['events'] | limit 20")
+	if err == nil || !strings.Contains(err.Error(), "no cassette recorded") {
+		t.Errorf("Expected missing cassette error, got %v", err)
+	}
+}
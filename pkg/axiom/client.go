@@ -3,32 +3,63 @@ package axiom
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/axiomhq/axiom-go/axiom"
 	"github.com/axiomhq/axiom-go/axiom/query"
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom/cassette"
 )
 
 // Client wraps the Axiom client for our specific use cases
+//
+// A Client is safe for concurrent use and is meant to be shared, so that
+// connections are reused and the concurrency limit applies to all callers.
 type Client struct {
 	client *axiom.Client
 	config *AxiomConfig
+
+	// querySlots limits the number of concurrently running queries
+	querySlots chan struct{}
+
+	// inflight holds running queries by APL, so identical concurrent
+	// queries are only executed once
+	inflightMu sync.Mutex
+	inflight   map[string]*inflightQuery
 }
 
 // AxiomConfig represents Axiom configuration (avoiding import cycle)
 type AxiomConfig struct {
-	Token   string
-	OrgID   string
-	Dataset string
-	URL     string
+	Token     string
+	OrgID     string
+	Dataset   string
+	URL       string
+	RecordDir string // Record responses as cassettes in this directory
+	ReplayDir string // Serve responses from cassettes in this directory instead of Axiom
+
+	MaxConcurrentQueries int // Maximum number of queries running at once, zero means unlimited
 }
 
+// replayToken is used in replay mode when no token is configured, since the
+// Axiom client refuses to start without a well-formed token
+const replayToken = "xaat-replay"
+
 // NewClient creates a new Axiom client from config
 func NewClient(config *AxiomConfig) *Client {
+	token := config.Token
+	if token == "" && config.ReplayDir != "" {
+		token = replayToken
+	}
+
 	opts := []axiom.Option{
-		axiom.SetToken(config.Token),
+		axiom.SetToken(token),
+		// Configuration is already resolved from env vars by the config package
+		axiom.SetNoEnv(),
+		// Retries are handled by doRetry, so they stop when the context is done
+		axiom.SetNoRetry(),
 	}
 	if config.URL != "" {
 		opts = append(opts, axiom.SetURL(config.URL))
@@ -36,6 +67,16 @@ func NewClient(config *AxiomConfig) *Client {
 	if config.OrgID != "" {
 		opts = append(opts, axiom.SetOrganizationID(config.OrgID))
 	}
+	switch {
+	case config.ReplayDir != "":
+		opts = append(opts, axiom.SetClient(&http.Client{
+			Transport: cassette.NewReplayer(config.ReplayDir),
+		}))
+	case config.RecordDir != "":
+		httpClient := axiom.DefaultHTTPClient()
+		httpClient.Transport = cassette.NewRecorder(config.RecordDir, httpClient.Transport)
+		opts = append(opts, axiom.SetClient(httpClient))
+	}
 
 	client, err := axiom.NewClient(opts...)
 	if err != nil {
@@ -43,14 +84,45 @@ func NewClient(config *AxiomConfig) *Client {
 		panic(fmt.Sprintf("failed to create axiom client: %v", err))
 	}
 
-	return &Client{
-		client: client,
-		config: config,
+	c := &Client{
+		client:   client,
+		config:   config,
+		inflight: make(map[string]*inflightQuery),
 	}
+	if config.MaxConcurrentQueries > 0 {
+		c.querySlots = make(chan struct{}, config.MaxConcurrentQueries)
+	}
+	return c
+}
+
+// QueryResult is the result of an APL query. It extends the axiom query
+// result with the status details Axiom reports beyond query.Status.
+type QueryResult struct {
+	query.Result
+	Details QueryDetails
 }
 
-// QueryResult is an alias for the axiom query result
-type QueryResult = query.Result
+// QueryDetails describes the cost of a query and whether its result is
+// complete
+type QueryDetails struct {
+	BlocksExamined uint64         // Storage blocks read by the query
+	BlocksCached   uint64         // Blocks served from Axiom's cache
+	BlocksMatched  uint64         // Blocks containing matching rows
+	BytesScanned   uint64         // Bytes read by the query, zero if not reported
+	NumGroups      uint32         // Number of groups of an aggregation
+	IsPartial      bool           // The query stopped before all data was scanned
+	IsEstimate     bool           // Aggregated values are estimates
+	Messages       []QueryMessage // Warnings and errors reported by Axiom
+}
+
+// QueryMessage is a message Axiom attaches to a query result, e.g. when a
+// limit was hit
+type QueryMessage struct {
+	Priority string `json:"priority"`
+	Count    int    `json:"count"`
+	Code     string `json:"code"`
+	Text     string `json:"msg"`
+}
 
 // StarredQuery represents a starred query from Axiom
 type StarredQuery struct {
@@ -68,22 +140,166 @@ type StarredQueryContent struct {
 	APL string `json:"apl"`
 }
 
-// ExecuteQuery executes an APL query and returns the result
-func (c *Client) ExecuteQuery(apl string) (*QueryResult, error) {
-	ctx := context.Background()
+// inflightQuery is a query shared by all callers executing the same APL
+type inflightQuery struct {
+	done    chan struct{}
+	result  *QueryResult
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
 
-	result, err := c.client.Query(ctx, apl)
+// ExecuteQuery executes an APL query and returns the result. The query is
+// aborted when ctx is cancelled or its deadline is exceeded.
+//
+// Identical queries running at the same time are coalesced into a single
+// Axiom query whose result is shared by all callers. The shared query is only
+// cancelled once every caller has given up on it.
+func (c *Client) ExecuteQuery(ctx context.Context, apl string) (*QueryResult, error) {
+	c.inflightMu.Lock()
+	call, ok := c.inflight[apl]
+	if !ok {
+		// Detach from the first caller's cancellation, since later callers
+		// may still be waiting for the result
+		queryCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		call = &inflightQuery{done: make(chan struct{}), cancel: cancel}
+		c.inflight[apl] = call
+
+		go func() {
+			call.result, call.err = c.executeWithSlot(queryCtx, apl)
+			c.inflightMu.Lock()
+			if c.inflight[apl] == call {
+				delete(c.inflight, apl)
+			}
+			c.inflightMu.Unlock()
+			cancel()
+			close(call.done)
+		}()
+	}
+	call.waiters++
+	c.inflightMu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.result, call.err
+	case <-ctx.Done():
+		c.inflightMu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			// Nobody is waiting anymore, so later callers start a new query
+			if c.inflight[apl] == call {
+				delete(c.inflight, apl)
+			}
+			call.cancel()
+		}
+		c.inflightMu.Unlock()
+		return nil, fmt.Errorf("query execution failed: %w", ctx.Err())
+	}
+}
+
+// executeWithSlot waits for a free query slot and executes the query
+func (c *Client) executeWithSlot(ctx context.Context, apl string) (*QueryResult, error) {
+	if c.querySlots != nil {
+		select {
+		case c.querySlots <- struct{}{}:
+			defer func() { <-c.querySlots }()
+		case <-ctx.Done():
+			return nil, fmt.Errorf("query execution failed: %w", ctx.Err())
+		}
+	}
+	return c.executeQuery(ctx, apl)
+}
+
+// executeQuery sends a single APL query to Axiom
+func (c *Client) executeQuery(ctx context.Context, apl string) (*QueryResult, error) {
+	req, err := c.client.NewRequest(ctx, http.MethodPost, "/v1/datasets/_apl?format=tabular", aplQueryRequest{APL: apl})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create query request: %w", err)
+	}
+
+	// Queries only read data, so they are safe to retry
+	var res aplQueryResponse
+	resp, err := c.doRetry(ctx, req, &res)
 	if err != nil {
 		return nil, fmt.Errorf("query execution failed: %w", err)
 	}
 
+	result := &QueryResult{
+		Result: query.Result{
+			Tables: res.Tables,
+			Status: query.Status{
+				MinCursor:    res.Status.MinCursor,
+				MaxCursor:    res.Status.MaxCursor,
+				ElapsedTime:  time.Duration(res.Status.ElapsedTime) * time.Microsecond,
+				RowsExamined: res.Status.RowsExamined,
+				RowsMatched:  res.Status.RowsMatched,
+			},
+			TraceID: resp.TraceID(),
+		},
+		Details: QueryDetails{
+			BlocksExamined: res.Status.BlocksExamined,
+			BlocksCached:   res.Status.BlocksCached,
+			BlocksMatched:  res.Status.BlocksMatched,
+			BytesScanned:   res.Status.BytesScanned,
+			NumGroups:      res.Status.NumGroups,
+			IsPartial:      res.Status.IsPartial,
+			IsEstimate:     res.Status.IsEstimate,
+			Messages:       res.Status.Messages,
+		},
+	}
+
+	slog.Info("Query completed",
+		"trace_id", result.TraceID,
+		"elapsed", result.Status.ElapsedTime,
+		"rows_examined", result.Status.RowsExamined,
+		"rows_matched", result.Status.RowsMatched,
+		"blocks_examined", result.Details.BlocksExamined,
+		"blocks_cached", result.Details.BlocksCached,
+		"bytes_scanned", result.Details.BytesScanned,
+		"is_partial", result.Details.IsPartial,
+		"is_estimate", result.Details.IsEstimate,
+		"messages", len(result.Details.Messages))
+
 	return result, nil
 }
 
-// StarredQueries fetches all starred queries from Axiom
-func (c *Client) StarredQueries() ([]StarredQuery, error) {
+// aplQueryRequest is the request body of the APL query endpoint
+type aplQueryRequest struct {
+	APL string `json:"apl"`
+}
+
+// aplQueryResponse is the tabular response of the APL query endpoint.
+//
+// The status is decoded into our own type instead of query.Status, because
+// query.Status.UnmarshalJSON recurses infinitely with the encoding/json
+// implementation of newer Go releases.
+type aplQueryResponse struct {
+	Tables []query.Table  `json:"tables"`
+	Status aplQueryStatus `json:"status"`
+}
+
+type aplQueryStatus struct {
+	MinCursor      string         `json:"minCursor"`
+	MaxCursor      string         `json:"maxCursor"`
+	ElapsedTime    int64          `json:"elapsedTime"` // Microseconds
+	RowsExamined   uint64         `json:"rowsExamined"`
+	RowsMatched    uint64         `json:"rowsMatched"`
+	BlocksExamined uint64         `json:"blocksExamined"`
+	BlocksCached   uint64         `json:"blocksCached"`
+	BlocksMatched  uint64         `json:"blocksMatched"`
+	BytesScanned   uint64         `json:"bytesScanned"`
+	NumGroups      uint32         `json:"numGroups"`
+	IsPartial      bool           `json:"isPartial"`
+	IsEstimate     bool           `json:"isEstimate"`
+	Messages       []QueryMessage `json:"messages"`
+}
+
+// StarredQueries fetches the starred queries of every user of the
+// organization from Axiom. Filtering by owner and other fields is left to the
+// caller, see config.QueryFilter.
+func (c *Client) StarredQueries(ctx context.Context) ([]StarredQuery, error) {
 	// Use context with 8-second timeout to leave buffer for the outer 10-second timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 8*time.Second)
 	defer cancel()
 
 	// Construct the full URL with query parameters
@@ -123,12 +339,88 @@ func (c *Client) StarredQueries() ([]StarredQuery, error) {
 		req.Header.Set("X-AXIOM-ORG-ID", c.config.OrgID)
 	}
 
-	// Use the axiom client's Do method which handles retries and error parsing
+	// Use the axiom client's Do method for error parsing, with our retries
 	var result []StarredQuery
-	_, err = c.client.Do(req, &result)
+	_, err = c.do(ctx, req, &result)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch starred queries: %w", err)
 	}
 
 	return result, nil
 }
+
+// starredQueryRequest is the request body of the starred query create and
+// update endpoints
+type starredQueryRequest struct {
+	Name     string                 `json:"name"`
+	Kind     string                 `json:"kind"`
+	Dataset  string                 `json:"dataset,omitempty"`
+	Query    StarredQueryContent    `json:"query"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Who      string                 `json:"who,omitempty"`
+}
+
+// CreateStarredQuery creates a starred query and returns it with its ID
+func (c *Client) CreateStarredQuery(ctx context.Context, sq StarredQuery) (*StarredQuery, error) {
+	req, err := c.client.NewRequest(ctx, http.MethodPost, "/v2/apl-starred-queries", newStarredQueryRequest(sq))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Not retried, since Axiom may have stored the query before failing
+	var created StarredQuery
+	if _, err := c.do(ctx, req, &created); err != nil {
+		return nil, fmt.Errorf("failed to create starred query %q: %w", sq.Name, err)
+	}
+	return &created, nil
+}
+
+// UpdateStarredQuery replaces the starred query with the ID of sq
+func (c *Client) UpdateStarredQuery(ctx context.Context, sq StarredQuery) (*StarredQuery, error) {
+	path, err := url.JoinPath("/v2/apl-starred-queries", sq.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct URL: %w", err)
+	}
+	req, err := c.client.NewRequest(ctx, http.MethodPut, path, newStarredQueryRequest(sq))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	var updated StarredQuery
+	if _, err := c.do(ctx, req, &updated); err != nil {
+		return nil, fmt.Errorf("failed to update starred query %q: %w", sq.Name, err)
+	}
+	return &updated, nil
+}
+
+// DeleteStarredQuery deletes the starred query with the given ID
+func (c *Client) DeleteStarredQuery(ctx context.Context, id string) error {
+	path, err := url.JoinPath("/v2/apl-starred-queries", id)
+	if err != nil {
+		return fmt.Errorf("failed to construct URL: %w", err)
+	}
+	req, err := c.client.NewRequest(ctx, http.MethodDelete, path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if _, err := c.do(ctx, req, nil); err != nil {
+		return fmt.Errorf("failed to delete starred query %s: %w", id, err)
+	}
+	return nil
+}
+
+func newStarredQueryRequest(sq StarredQuery) starredQueryRequest {
+	kind := sq.Kind
+	if kind == "" {
+		kind = "apl"
+	}
+	return starredQueryRequest{
+		Name:     sq.Name,
+		Kind:     kind,
+		Dataset:  sq.Dataset,
+		Query:    sq.Query,
+		Metadata: sq.Metadata,
+		Who:      sq.Who,
+	}
+}
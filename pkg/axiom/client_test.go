@@ -0,0 +1,263 @@
+package axiom_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	axiomgo "github.com/axiomhq/axiom-go/axiom"
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom"
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom/axiomtest"
+)
+
+func TestStarredQueries(t *testing.T) {
+	srv := axiomtest.NewServer(t)
+	if err := srv.LoadFixtures(axiomtest.DefaultFixtures()); err != nil {
+		t.Fatalf("Failed to load fixtures: %v", err)
+	}
+
+	queries, err := srv.Client().StarredQueries(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to fetch starred queries: %v", err)
+	}
+	if len(queries) != 2 {
+		t.Fatalf("Expected 2 starred queries, got %d", len(queries))
+	}
+	if queries[0].Name != "Entity data" {
+		t.Errorf("Expected first query to be 'Entity data', got '%s'", queries[0].Name)
+	}
+
+	reqs := srv.Requests()
+	if len(reqs) != 1 || reqs[0].Query != "who=all" {
+		t.Errorf("Expected a single request with who=all, got %+v", reqs)
+	}
+}
+
+func TestStarredQueryCRUD(t *testing.T) {
+	srv := axiomtest.NewServer(t)
+	client := srv.Client()
+	ctx := context.Background()
+
+	created, err := client.CreateStarredQuery(ctx, axiom.StarredQuery{
+		Name:  "Errors",
+		Query: axiom.StarredQueryContent{APL: "['events'] | where level == 'error'"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create starred query: %v", err)
+	}
+	if created.ID == "" || created.Kind != "apl" {
+		t.Errorf("Expected created query with ID and kind apl, got %+v", created)
+	}
+
+	created.Query.APL = "['events'] | where level == 'warn'"
+	if _, err := client.UpdateStarredQuery(ctx, *created); err != nil {
+		t.Fatalf("Failed to update starred query: %v", err)
+	}
+	queries := srv.StarredQueries()
+	if len(queries) != 1 || queries[0].Query.APL != created.Query.APL {
+		t.Errorf("Expected updated query, got %+v", queries)
+	}
+
+	if err := client.DeleteStarredQuery(ctx, created.ID); err != nil {
+		t.Fatalf("Failed to delete starred query: %v", err)
+	}
+	if queries := srv.StarredQueries(); len(queries) != 0 {
+		t.Errorf("Expected no starred queries after delete, got %+v", queries)
+	}
+
+	if err := client.DeleteStarredQuery(ctx, created.ID); err == nil {
+		t.Error("Expected error deleting unknown starred query")
+	}
+}
+
+func TestCreateStarredQueryNotRetried(t *testing.T) {
+	srv := axiomtest.NewServer(t)
+	srv.InjectFault(axiomtest.StarredQueriesPath, axiomtest.Fault{Status: http.StatusInternalServerError, Committed: true})
+
+	_, err := srv.Client().CreateStarredQuery(context.Background(), axiom.StarredQuery{
+		Name:  "Errors",
+		Query: axiom.StarredQueryContent{APL: "['events'] | where level == 'error'"},
+	})
+	if err == nil {
+		t.Fatal("Expected the server error to be returned")
+	}
+	if queries := srv.StarredQueries(); len(queries) != 1 {
+		t.Errorf("Expected exactly one starred query, got %+v", queries)
+	}
+}
+
+func TestExecuteQuery(t *testing.T) {
+	srv := axiomtest.NewServer(t)
+	if err := srv.LoadFixtures(axiomtest.DefaultFixtures()); err != nil {
+		t.Fatalf("Failed to load fixtures: %v", err)
+	}
+
+	result, err := srv.Client().ExecuteQuery(context.Background(), "['events'] | limit 10")
+	if err != nil {
+		t.Fatalf("Failed to execute query: %v", err)
+	}
+	if len(result.Tables) != 1 {
+		t.Fatalf("Expected 1 table, got %d", len(result.Tables))
+	}
+	if got := len(result.Tables[0].Columns[0]); got != 4 {
+		t.Errorf("Expected 4 rows, got %d", got)
+	}
+	if result.Status.ElapsedTime != 120*time.Millisecond {
+		t.Errorf("Expected elapsed time 120ms, got %v", result.Status.ElapsedTime)
+	}
+}
+
+func TestExecuteQueryUnauthenticated(t *testing.T) {
+	srv := axiomtest.NewServer(t)
+	config := srv.Config()
+	config.Token = "xaat-wrong-token"
+
+	_, err := axiom.NewClient(config).ExecuteQuery(context.Background(), "['events']")
+	if err == nil {
+		t.Fatal("Expected error for invalid token")
+	}
+}
+
+func TestExecuteQueryInjectedFaults(t *testing.T) {
+	srv := axiomtest.NewServer(t)
+	srv.SetDefaultResult(axiomtest.Result{})
+
+	// A single 5xx is retried by the Axiom client
+	srv.InjectFault(axiomtest.QueryPath, axiomtest.Fault{Status: http.StatusBadGateway})
+	if _, err := srv.Client().ExecuteQuery(context.Background(), "['events']"); err != nil {
+		t.Errorf("Expected 502 to be retried, got %v", err)
+	}
+
+	// Rate limited queries are retried once the limit resets
+	srv.InjectFault(axiomtest.QueryPath, axiomtest.Fault{Status: http.StatusTooManyRequests, RetryAfter: time.Second})
+	if _, err := srv.Client().ExecuteQuery(context.Background(), "['events']"); err != nil {
+		t.Errorf("Expected 429 to be retried, got %v", err)
+	}
+
+	// Unless the limit resets too far in the future
+	srv.InjectFault(axiomtest.QueryPath, axiomtest.Fault{Status: http.StatusTooManyRequests, RetryAfter: time.Minute})
+	var limitErr axiomgo.LimitError
+	if _, err := srv.Client().ExecuteQuery(context.Background(), "['events']"); !errors.As(err, &limitErr) {
+		t.Errorf("Expected limit error for 429 response, got %v", err)
+	}
+
+	// Slow responses are aborted when the context deadline is exceeded
+	srv.InjectFault(axiomtest.QueryPath, axiomtest.Fault{Delay: time.Second})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := srv.Client().ExecuteQuery(ctx, "['events']")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestExecuteQueryCoalescesIdenticalQueries(t *testing.T) {
+	srv := axiomtest.NewServer(t)
+	srv.SetDefaultResult(axiomtest.Result{})
+	srv.InjectFault(axiomtest.QueryPath, axiomtest.Fault{Delay: 200 * time.Millisecond})
+	client := srv.Client()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.ExecuteQuery(context.Background(), "['events'] | count")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	if got := srv.QueryCount(); got != 1 {
+		t.Errorf("Expected identical queries to be coalesced into 1, got %d", got)
+	}
+}
+
+func TestExecuteQueryCoalescedCallerCancels(t *testing.T) {
+	srv := axiomtest.NewServer(t)
+	srv.SetDefaultResult(axiomtest.Result{})
+	srv.InjectFault(axiomtest.QueryPath, axiomtest.Fault{Delay: 200 * time.Millisecond})
+	client := srv.Client()
+
+	// The first caller gives up, but the second one still gets the result
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := client.ExecuteQuery(ctx, "['events'] | count")
+		firstErr <- err
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	if _, err := client.ExecuteQuery(context.Background(), "['events'] | count"); err != nil {
+		t.Errorf("Expected second caller to succeed, got %v", err)
+	}
+	if err := <-firstErr; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected first caller to time out, got %v", err)
+	}
+}
+
+func TestExecuteQueryConcurrencyLimit(t *testing.T) {
+	srv := axiomtest.NewServer(t)
+	srv.SetDefaultResult(axiomtest.Result{})
+	srv.InjectFault(axiomtest.QueryPath, axiomtest.Fault{Delay: 50 * time.Millisecond, Times: 6})
+
+	config := srv.Config()
+	config.MaxConcurrentQueries = 2
+	client := axiom.NewClient(config)
+
+	var wg sync.WaitGroup
+	for i := range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.ExecuteQuery(context.Background(), fmt.Sprintf("['events'] | take %d", i)); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := srv.QueryCount(); got != 6 {
+		t.Errorf("Expected 6 queries, got %d", got)
+	}
+	if got := srv.PeakConcurrentQueries(); got > 2 {
+		t.Errorf("Expected at most 2 concurrent queries, got %d", got)
+	}
+}
+
+func TestExecuteQueryDetails(t *testing.T) {
+	srv := axiomtest.NewServer(t)
+	srv.SetDefaultResult(axiomtest.Result{
+		Details: axiom.QueryDetails{
+			BlocksExamined: 12,
+			BlocksCached:   3,
+			IsPartial:      true,
+			Messages:       []axiom.QueryMessage{{Priority: "warn", Code: "default_limit_warning", Text: "limit 1000 applied"}},
+		},
+	})
+
+	result, err := srv.Client().ExecuteQuery(context.Background(), "['events']")
+	if err != nil {
+		t.Fatalf("Failed to execute query: %v", err)
+	}
+
+	details := result.Details
+	if details.BlocksExamined != 12 || details.BlocksCached != 3 || !details.IsPartial {
+		t.Errorf("Unexpected details: %+v", details)
+	}
+	if len(details.Messages) != 1 || details.Messages[0].Code != "default_limit_warning" {
+		t.Errorf("Expected limit message, got %+v", details.Messages)
+	}
+}
@@ -0,0 +1,79 @@
+package axiom
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+)
+
+// datasetSampleSize is the number of recent events used to describe a dataset
+const datasetSampleSize = 20
+
+// Dataset represents a dataset visible to the token
+type Dataset struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Who         string    `json:"who"`
+	Created     time.Time `json:"created"`
+}
+
+// Datasets fetches all datasets the token can read
+func (c *Client) Datasets(ctx context.Context) ([]Dataset, error) {
+	req, err := c.client.NewRequest(ctx, http.MethodGet, "/v2/datasets", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	var datasets []Dataset
+	if _, err := c.do(ctx, req, &datasets); err != nil {
+		return nil, fmt.Errorf("failed to fetch datasets: %w", err)
+	}
+
+	return datasets, nil
+}
+
+// DatasetsResult converts a list of datasets into a query result, so it can be
+// formatted like any other result
+func DatasetsResult(datasets []Dataset) *QueryResult {
+	names := make([]any, 0, len(datasets))
+	descriptions := make([]any, 0, len(datasets))
+	created := make([]any, 0, len(datasets))
+	for _, dataset := range datasets {
+		names = append(names, dataset.Name)
+		descriptions = append(descriptions, dataset.Description)
+		created = append(created, dataset.Created.UTC().Format(time.RFC3339))
+	}
+
+	return &QueryResult{
+		Result: query.Result{
+			Tables: []query.Table{{
+				Name: "datasets",
+				Fields: []query.Field{
+					{Name: "name", Type: "string"},
+					{Name: "description", Type: "string"},
+					{Name: "created", Type: "datetime"},
+				},
+				Columns: []query.Column{names, descriptions, created},
+			}},
+		},
+	}
+}
+
+// DescribeDatasetAPL returns the APL query used to sample recent events of a
+// dataset, from which field names, types and example values are derived
+func DescribeDatasetAPL(dataset string) string {
+	// Escape the dataset name for use in a quoted APL identifier
+	escaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(dataset)
+	return fmt.Sprintf("['%s']\n| where _time > ago(7d)\n| sort by _time desc\n| take %d",
+		escaped, datasetSampleSize)
+}
+
+// DescribeDataset samples the most recent events of a dataset
+func (c *Client) DescribeDataset(ctx context.Context, dataset string) (*QueryResult, error) {
+	return c.ExecuteQuery(ctx, DescribeDatasetAPL(dataset))
+}
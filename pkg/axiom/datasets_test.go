@@ -0,0 +1,40 @@
+package axiom_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom"
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom/axiomtest"
+)
+
+func TestDatasets(t *testing.T) {
+	srv := axiomtest.NewServer(t)
+	if err := srv.LoadFixtures(axiomtest.DefaultFixtures()); err != nil {
+		t.Fatalf("Failed to load fixtures: %v", err)
+	}
+
+	datasets, err := srv.Client().Datasets(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to fetch datasets: %v", err)
+	}
+	if len(datasets) != 2 {
+		t.Fatalf("Expected 2 datasets, got %d", len(datasets))
+	}
+	if datasets[0].Name != "events" || datasets[0].Description != "User activity events" {
+		t.Errorf("Unexpected first dataset: %+v", datasets[0])
+	}
+
+	result := axiom.DatasetsResult(datasets)
+	if got := len(result.Tables[0].Columns[0]); got != 2 {
+		t.Errorf("Expected 2 rows in dataset result, got %d", got)
+	}
+}
+
+func TestDescribeDatasetAPL(t *testing.T) {
+	apl := axiom.DescribeDatasetAPL("it's")
+	want := "['it\\'s']\n| where _time > ago(7d)\n| sort by _time desc\n| take 20"
+	if apl != want {
+		t.Errorf("Expected %q, got %q", want, apl)
+	}
+}
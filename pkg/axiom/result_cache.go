@@ -0,0 +1,157 @@
+package axiom
+
+import (
+	"container/list"
+	"encoding/json"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// relativeTimeRegex matches APL functions whose value depends on when the
+// query runs
+var relativeTimeRegex = regexp.MustCompile(`\b(ago|now)\s*\(`)
+
+// ResultCache caches query results keyed on the rendered APL. Entries expire
+// after a TTL, and the least recently used entries are evicted when the
+// estimated size of all cached results exceeds the memory bound.
+type ResultCache struct {
+	ttl         time.Duration
+	relativeTTL time.Duration
+	maxBytes    int
+
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	lru     *list.List // Front is most recently used
+
+	now func() time.Time
+}
+
+// CachedResult is a query result together with the time it was fetched
+type CachedResult struct {
+	Result    *QueryResult
+	FetchedAt time.Time
+}
+
+// Age returns how old the cached data is
+func (r *CachedResult) Age(now time.Time) time.Duration {
+	return now.Sub(r.FetchedAt)
+}
+
+type cacheEntry struct {
+	apl       string
+	result    CachedResult
+	expiresAt time.Time
+	size      int
+}
+
+// NewResultCache creates a result cache. Queries using relative times such as
+// ago() or now() expire after relativeTTL instead of ttl, since the window
+// they cover moves with the clock. A zero ttl disables the cache.
+func NewResultCache(ttl, relativeTTL time.Duration, maxBytes int) *ResultCache {
+	return &ResultCache{
+		ttl:         ttl,
+		relativeTTL: relativeTTL,
+		maxBytes:    maxBytes,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+		now:         time.Now,
+	}
+}
+
+// Get returns the cached result for apl, if present and not expired
+func (c *ResultCache) Get(apl string) (*CachedResult, bool) {
+	if c == nil || c.ttl <= 0 {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[apl]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.remove(elem)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+	result := entry.result
+	return &result, true
+}
+
+// Put stores the result for apl. Results larger than the memory bound are
+// not cached.
+func (c *ResultCache) Put(apl string, result *QueryResult) {
+	if c == nil || c.ttl <= 0 {
+		return
+	}
+
+	ttl := c.ttl
+	if IsRelativeQuery(apl) {
+		ttl = min(c.relativeTTL, c.ttl)
+		if ttl <= 0 {
+			return
+		}
+	}
+
+	size := estimateSize(apl, result)
+	if c.maxBytes > 0 && size > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[apl]; ok {
+		c.remove(elem)
+	}
+
+	now := c.now()
+	entry := &cacheEntry{
+		apl:       apl,
+		result:    CachedResult{Result: result, FetchedAt: now},
+		expiresAt: now.Add(ttl),
+		size:      size,
+	}
+	c.entries[apl] = c.lru.PushFront(entry)
+	c.size += size
+
+	// Evict least recently used entries until we are within the bound
+	for c.maxBytes > 0 && c.size > c.maxBytes {
+		c.remove(c.lru.Back())
+	}
+}
+
+// Len returns the number of cached results
+func (c *ResultCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+func (c *ResultCache) remove(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*cacheEntry)
+	delete(c.entries, entry.apl)
+	c.size -= entry.size
+}
+
+// IsRelativeQuery reports whether the APL uses relative times like ago() or
+// now(), which makes its result depend on when it runs
+func IsRelativeQuery(apl string) bool {
+	return relativeTimeRegex.MatchString(apl)
+}
+
+// estimateSize estimates the memory used by a cached result from the size of
+// its JSON encoding
+func estimateSize(apl string, result *QueryResult) int {
+	data, err := json.Marshal(result.Tables)
+	if err != nil {
+		return len(apl)
+	}
+	return len(apl) + len(data)
+}
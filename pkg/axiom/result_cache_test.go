@@ -0,0 +1,84 @@
+package axiom
+
+import (
+	"testing"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+)
+
+func testResult(rows int) *QueryResult {
+	column := make(query.Column, rows)
+	for i := range column {
+		column[i] = "value"
+	}
+	return &QueryResult{
+		Result: query.Result{
+			Tables: []query.Table{{
+				Fields:  []query.Field{{Name: "v", Type: "string"}},
+				Columns: []query.Column{column},
+			}},
+		},
+	}
+}
+
+func TestResultCacheExpiry(t *testing.T) {
+	now := time.Date(2025, 6, 25, 12, 0, 0, 0, time.UTC)
+	cache := NewResultCache(5*time.Minute, 30*time.Second, 0)
+	cache.now = func() time.Time { return now }
+
+	absolute := "['events'] | where _time > datetime(2025-06-25T00:00:00Z)"
+	relative := "['events'] | where _time > ago(1h)"
+	cache.Put(absolute, testResult(1))
+	cache.Put(relative, testResult(1))
+
+	now = now.Add(time.Minute)
+	cached, ok := cache.Get(absolute)
+	if !ok {
+		t.Fatal("Expected absolute query to still be cached")
+	}
+	if age := cached.Age(now); age != time.Minute {
+		t.Errorf("Expected age 1m, got %v", age)
+	}
+	if _, ok := cache.Get(relative); ok {
+		t.Error("Expected relative query to expire after the shorter TTL")
+	}
+
+	now = now.Add(5 * time.Minute)
+	if _, ok := cache.Get(absolute); ok {
+		t.Error("Expected absolute query to expire after the TTL")
+	}
+}
+
+func TestResultCacheMemoryBound(t *testing.T) {
+	size := estimateSize("a", testResult(100))
+	cache := NewResultCache(time.Minute, time.Minute, 2*size+10)
+
+	cache.Put("a", testResult(100))
+	cache.Put("b", testResult(100))
+	cache.Get("a") // Make "b" the least recently used entry
+	cache.Put("c", testResult(100))
+
+	if cache.Len() != 2 {
+		t.Errorf("Expected 2 cached results, got %d", cache.Len())
+	}
+	if _, ok := cache.Get("b"); ok {
+		t.Error("Expected least recently used result to be evicted")
+	}
+	if _, ok := cache.Get("a"); !ok {
+		t.Error("Expected recently used result to be kept")
+	}
+
+	cache.Put("huge", testResult(10000))
+	if _, ok := cache.Get("huge"); ok {
+		t.Error("Expected result larger than the memory bound not to be cached")
+	}
+}
+
+func TestResultCacheDisabled(t *testing.T) {
+	cache := NewResultCache(0, 0, 0)
+	cache.Put("a", testResult(1))
+	if _, ok := cache.Get("a"); ok {
+		t.Error("Expected zero TTL to disable the cache")
+	}
+}
@@ -0,0 +1,89 @@
+package axiom
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom"
+)
+
+const (
+	maxAttempts         = 4
+	initialRetryBackoff = 200 * time.Millisecond
+
+	// maxRetryWait is the longest we wait for a rate limit to reset before
+	// giving up and reporting the rate limit to the caller
+	maxRetryWait = 10 * time.Second
+)
+
+// do sends the request using the Axiom client. Idempotent requests (GET,
+// HEAD, PUT and DELETE) are retried like doRetry, other requests are sent
+// once, since a failed response doesn't mean the change wasn't made.
+func (c *Client) do(ctx context.Context, req *http.Request, v any) (*axiom.Response, error) {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return c.doRetry(ctx, req, v)
+	}
+	return c.client.Do(req, v)
+}
+
+// doRetry sends the request using the Axiom client, retrying rate limited
+// requests and server errors with exponential backoff. Retries stop as soon
+// as ctx is done. Only use it for requests that are safe to repeat.
+func (c *Client) doRetry(ctx context.Context, req *http.Request, v any) (*axiom.Response, error) {
+	backoff := initialRetryBackoff
+	for attempt := 1; ; attempt++ {
+		resp, err := c.client.Do(req, v)
+		if err == nil || attempt >= maxAttempts {
+			return resp, err
+		}
+
+		wait, ok := retryWait(resp, err, backoff)
+		if !ok {
+			return resp, err
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return resp, err
+		}
+		backoff *= 2
+
+		// Reset the request body so it can be sent again
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return resp, err
+			}
+		}
+	}
+}
+
+// retryWait returns how long to wait before retrying a failed request, and
+// whether the request should be retried at all
+func retryWait(resp *axiom.Response, err error, backoff time.Duration) (time.Duration, bool) {
+	var limitErr axiom.LimitError
+	if errors.As(err, &limitErr) {
+		wait := backoff
+		if !limitErr.Limit.Reset.IsZero() {
+			wait = max(time.Until(limitErr.Limit.Reset), backoff)
+		}
+		if resp != nil {
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+				wait = max(time.Duration(seconds)*time.Second, backoff)
+			}
+		}
+		return wait, wait <= maxRetryWait
+	}
+
+	var httpErr axiom.HTTPError
+	if errors.As(err, &httpErr) {
+		retryable := httpErr.Status == http.StatusTooManyRequests || httpErr.Status >= http.StatusInternalServerError
+		return backoff, retryable
+	}
+
+	return 0, false
+}
@@ -0,0 +1,308 @@
+package caxiom
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConstraintKind is the kind of a machine-enforced constraint
+type ConstraintKind string
+
+const (
+	ConstraintMaxWindow      ConstraintKind = "max-window"       // End is at most Max after Start
+	ConstraintStartBeforeEnd ConstraintKind = "start-before-end" // Start is before End
+	ConstraintNotInFuture    ConstraintKind = "not-in-future"    // Param is not after now
+	ConstraintMaxLookback    ConstraintKind = "max-lookback"     // Param is at most Max before now
+)
+
+// Constraint restricts the time window of a curated tool. Constraints written
+// as plain text are only shown to the agent, constraints with a Kind are also
+// checked before the query runs.
+type Constraint struct {
+	Text        string         `yaml:"-"` // Free text constraint
+	Kind        ConstraintKind `yaml:"Kind"`
+	Start       string         `yaml:"Start,omitempty"`       // Start parameter of max-window and start-before-end
+	End         string         `yaml:"End,omitempty"`         // End parameter of max-window and start-before-end
+	Param       string         `yaml:"Param,omitempty"`       // Parameter of not-in-future and max-lookback
+	Max         string         `yaml:"Max,omitempty"`         // Duration of max-window and max-lookback, e.g. 24h or 30d
+	Description string         `yaml:"Description,omitempty"` // Reason shown to the agent
+}
+
+// UnmarshalYAML decodes a constraint from a plain string or a mapping
+func (c *Constraint) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*c = Constraint{Text: node.Value}
+		return nil
+	}
+	type plain Constraint
+	return node.Decode((*plain)(c))
+}
+
+// MarshalYAML encodes free text constraints as plain strings
+func (c Constraint) MarshalYAML() (any, error) {
+	if c.Kind == "" {
+		return c.Text, nil
+	}
+	type plain Constraint
+	return plain(c), nil
+}
+
+// String describes the constraint for the tool description
+func (c Constraint) String() string {
+	var text string
+	switch c.Kind {
+	case "":
+		return c.Text
+	case ConstraintMaxWindow:
+		text = fmt.Sprintf("%s must be at most %s after %s", c.End, c.Max, c.Start)
+	case ConstraintStartBeforeEnd:
+		text = fmt.Sprintf("%s must be before %s", c.Start, c.End)
+	case ConstraintNotInFuture:
+		text = fmt.Sprintf("%s must not be in the future", c.Param)
+	case ConstraintMaxLookback:
+		text = fmt.Sprintf("%s must be at most %s before now", c.Param, c.Max)
+	default:
+		text = string(c.Kind)
+	}
+	if c.Description != "" {
+		text += ", " + c.Description
+	}
+	return text
+}
+
+// params returns the parameters the constraint applies to
+func (c Constraint) params() []string {
+	switch c.Kind {
+	case ConstraintMaxWindow, ConstraintStartBeforeEnd:
+		return []string{c.Start, c.End}
+	case ConstraintNotInFuture, ConstraintMaxLookback:
+		return []string{c.Param}
+	}
+	return nil
+}
+
+// maxDuration returns the Max duration of the constraint
+func (c Constraint) maxDuration() time.Duration {
+	d, _ := parseDuration(strings.TrimSpace(c.Max))
+	return d
+}
+
+// validateConstraints checks that every constraint has a known kind, and
+// applies to date-time parameters of the query
+func validateConstraints(constraints []Constraint, params []ParameterDefinition) error {
+	types := make(map[string]ParamType, len(params))
+	for _, param := range params {
+		types[param.Name], _ = ParseParamType(param.Type)
+	}
+
+	for i, c := range constraints {
+		var err error
+		switch c.Kind {
+		case "":
+			continue
+		case ConstraintMaxWindow, ConstraintStartBeforeEnd:
+			if c.Start == "" || c.End == "" {
+				err = fmt.Errorf("needs Start and End parameters")
+			}
+		case ConstraintNotInFuture, ConstraintMaxLookback:
+			if c.Param == "" {
+				err = fmt.Errorf("needs a Param")
+			}
+		default:
+			err = fmt.Errorf("unknown kind %q, use one of max-window, start-before-end, not-in-future or max-lookback", c.Kind)
+		}
+		if err == nil {
+			for _, name := range c.params() {
+				paramType, ok := types[name]
+				if !ok {
+					err = fmt.Errorf("unknown parameter %s", name)
+					break
+				}
+				if paramType != ParamDateTime {
+					err = fmt.Errorf("parameter %s must be a date-time, got %s", name, paramType)
+					break
+				}
+			}
+		}
+		if err == nil && (c.Kind == ConstraintMaxWindow || c.Kind == ConstraintMaxLookback) {
+			if d, ok := parseDuration(strings.TrimSpace(c.Max)); !ok || d <= 0 {
+				err = fmt.Errorf("Max must be a positive duration such as 24h or 7d, got %q", c.Max)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("constraint %d (%s): %w", i+1, c.Kind, err)
+		}
+	}
+	return nil
+}
+
+// CheckConstraints checks the coerced arguments of a curated tool against its
+// constraints, relative to now. The error lists every violated constraint and
+// suggests the nearest window that satisfies all of them.
+func CheckConstraints(constraints []Constraint, values map[string]interface{}, now time.Time) error {
+	times := constraintTimes(constraints, values)
+
+	var errs []error
+	for _, c := range constraints {
+		if err := c.check(times, now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+
+	err := fmt.Errorf("invalid time window:\n%w", errors.Join(errs...))
+	if suggestion, ok := suggestWindow(constraints, times, now); ok {
+		var parts []string
+		for _, name := range constraintParams(constraints) {
+			if t, ok := suggestion[name]; ok {
+				parts = append(parts, name+"="+t.UTC().Format(time.RFC3339))
+			}
+		}
+		err = fmt.Errorf("%w\nNearest valid window: %s", err, strings.Join(parts, ", "))
+	}
+	return err
+}
+
+// constraintParams returns the parameters of all constraints, in order of
+// first use
+func constraintParams(constraints []Constraint) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, c := range constraints {
+		for _, name := range c.params() {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// constraintTimes parses the date-time arguments of constrained parameters
+func constraintTimes(constraints []Constraint, values map[string]interface{}) map[string]time.Time {
+	times := make(map[string]time.Time)
+	for _, name := range constraintParams(constraints) {
+		text, ok := values[name].(string)
+		if !ok {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339Nano, text); err == nil {
+			times[name] = t
+		}
+	}
+	return times
+}
+
+// check reports whether the times satisfy the constraint. Constraints on
+// parameters without a value are satisfied.
+func (c Constraint) check(times map[string]time.Time, now time.Time) error {
+	switch c.Kind {
+	case ConstraintMaxWindow:
+		start, okStart := times[c.Start]
+		end, okEnd := times[c.End]
+		if okStart && okEnd && end.Sub(start) > c.maxDuration() {
+			return fmt.Errorf("%s must be at most %s after %s, got %s", c.End, c.Max, c.Start, describeDuration(end.Sub(start)))
+		}
+	case ConstraintStartBeforeEnd:
+		start, okStart := times[c.Start]
+		end, okEnd := times[c.End]
+		if okStart && okEnd && !start.Before(end) {
+			return fmt.Errorf("%s must be before %s, got %s and %s", c.Start, c.End, formatTime(start), formatTime(end))
+		}
+	case ConstraintNotInFuture:
+		if t, ok := times[c.Param]; ok && t.After(now) {
+			return fmt.Errorf("%s must not be in the future, got %s which is %s after now (%s)", c.Param, formatTime(t), describeDuration(t.Sub(now)), formatTime(now))
+		}
+	case ConstraintMaxLookback:
+		if t, ok := times[c.Param]; ok && now.Sub(t) > c.maxDuration() {
+			return fmt.Errorf("%s must be at most %s before now (%s), got %s which is %s before now", c.Param, c.Max, formatTime(now), formatTime(t), describeDuration(now.Sub(t)))
+		}
+	}
+	return nil
+}
+
+// fix moves the times the least needed to satisfy the constraint, and reports
+// whether any time was moved. With shift, a time that is too early or too late
+// moves the whole window along with it, so the window keeps its length.
+func (c Constraint) fix(times map[string]time.Time, now time.Time, shift bool) bool {
+	if c.check(times, now) == nil {
+		return false
+	}
+	moveTo := func(name string, t time.Time) {
+		if !shift {
+			times[name] = t
+			return
+		}
+		delta := t.Sub(times[name])
+		for name := range times {
+			times[name] = times[name].Add(delta)
+		}
+	}
+
+	switch c.Kind {
+	case ConstraintMaxWindow:
+		// Keep the end, which is usually the most recent data
+		times[c.Start] = times[c.End].Add(-c.maxDuration())
+	case ConstraintStartBeforeEnd:
+		start, end := times[c.Start], times[c.End]
+		if start.After(end) {
+			times[c.Start], times[c.End] = end, start
+		} else {
+			times[c.Start] = end.Add(-time.Hour)
+		}
+	case ConstraintNotInFuture:
+		moveTo(c.Param, now.Truncate(time.Second))
+	case ConstraintMaxLookback:
+		// Leave a minute of slack, since the agent calls again a bit later
+		moveTo(c.Param, now.Add(-c.maxDuration()).Truncate(time.Minute).Add(time.Minute))
+	}
+	return true
+}
+
+// suggestWindow moves the times until they satisfy every constraint. It first
+// tries to keep the length of the window, then to move only the offending
+// times. It reports false if the constraints could not all be satisfied.
+func suggestWindow(constraints []Constraint, times map[string]time.Time, now time.Time) (map[string]time.Time, bool) {
+	for _, shift := range []bool{true, false} {
+		suggestion := make(map[string]time.Time, len(times))
+		for name, t := range times {
+			suggestion[name] = t.Truncate(time.Second)
+		}
+
+		for pass := 0; pass <= len(constraints); pass++ {
+			moved := false
+			for _, c := range constraints {
+				if c.fix(suggestion, now, shift) {
+					moved = true
+				}
+			}
+			if !moved {
+				return suggestion, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// formatTime formats a time for constraint errors
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// describeDuration formats a duration for constraint errors, rounded to the
+// largest unit it has at least two of, e.g. 36h or 12d
+func describeDuration(d time.Duration) string {
+	for _, u := range timespanUnits {
+		if d >= 2*u.unit {
+			return FormatTimespan(d.Round(u.unit))
+		}
+	}
+	return FormatTimespan(d.Round(time.Millisecond))
+}
@@ -0,0 +1,153 @@
+package caxiom
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const constrainedQuery = `['events'] | where _time between (datetime({{.StartTime}}) .. datetime({{.EndTime}}))
+
+// CuratedAxiomMCP:
+//   Params:
+//     - Name: StartTime
+//       Type: date-time
+//     - Name: EndTime
+//       Type: date-time
+//   Constraints:
+//     - Keep the window small
+//     - Kind: max-window
+//       Start: StartTime
+//       End: EndTime
+//       Max: 24h
+//       Description: to avoid query timing out
+//     - Kind: start-before-end
+//       Start: StartTime
+//       End: EndTime
+//     - Kind: not-in-future
+//       Param: EndTime
+//     - Kind: max-lookback
+//       Param: StartTime
+//       Max: 30d`
+
+func TestParseStarredQueryConstraints(t *testing.T) {
+	parsed, err := ParseStarredQuery("test-query", constrainedQuery)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+
+	want := []string{
+		"Keep the window small",
+		"EndTime must be at most 24h after StartTime, to avoid query timing out",
+		"StartTime must be before EndTime",
+		"EndTime must not be in the future",
+		"StartTime must be at most 30d before now",
+	}
+	constraints := parsed.Metadata.CuratedAxiomMCP.Constraints
+	if len(constraints) != len(want) {
+		t.Fatalf("Expected %d constraints, got %+v", len(want), constraints)
+	}
+	for i, w := range want {
+		if got := constraints[i].String(); got != w {
+			t.Errorf("Constraint %d: expected %q, got %q", i, w, got)
+		}
+	}
+}
+
+func TestParseStarredQueryInvalidConstraints(t *testing.T) {
+	tests := map[string]string{
+		"Kind: max-window\n//       Start: StartTime\n//       End: EndTime":              `constraint 1 (max-window): Max must be a positive duration such as 24h or 7d, got ""`,
+		"Kind: max-window\n//       Start: StartTime\n//       End: Id\n//       Max: 1h": "constraint 1 (max-window): parameter Id must be a date-time, got string",
+		"Kind: not-in-future\n//       Param: Until":                                      "constraint 1 (not-in-future): unknown parameter Until",
+		"Kind: start-before-end\n//       Start: StartTime":                               "constraint 1 (start-before-end): needs Start and End parameters",
+		"Kind: max-span\n//       Param: StartTime":                                       `constraint 1 (max-span): unknown kind "max-span"`,
+		"Kind: max-lookback\n//       Param: StartTime\n//       Max: forever":            `Max must be a positive duration such as 24h or 7d, got "forever"`,
+	}
+	for constraint, wantErr := range tests {
+		apl := "['events'] | where id == '{{.Id}}'\n\n// CuratedAxiomMCP:\n//   Params:\n//     - Name: Id\n//     - Name: StartTime\n//       Type: date-time\n//     - Name: EndTime\n//       Type: date-time\n//   Constraints:\n//     - " + constraint
+		if _, err := ParseStarredQuery("test-query", apl); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("Expected error %q, got %v", wantErr, err)
+		}
+	}
+}
+
+func TestCheckConstraints(t *testing.T) {
+	parsed, err := ParseStarredQuery("test-query", constrainedQuery)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+	constraints := parsed.Metadata.CuratedAxiomMCP.Constraints
+	now := time.Date(2025, 6, 26, 12, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		start, end string
+		wantErrs   []string
+		suggestion string
+	}{
+		{
+			name:  "valid",
+			start: "2025-06-25T12:00:00Z",
+			end:   "2025-06-26T12:00:00Z",
+		},
+		{
+			name:       "window too long",
+			start:      "2025-06-20T00:00:00Z",
+			end:        "2025-06-26T00:00:00Z",
+			wantErrs:   []string{"EndTime must be at most 24h after StartTime, got 6d"},
+			suggestion: "StartTime=2025-06-25T00:00:00Z, EndTime=2025-06-26T00:00:00Z",
+		},
+		{
+			name:       "reversed",
+			start:      "2025-06-26T00:00:00Z",
+			end:        "2025-06-25T00:00:00Z",
+			wantErrs:   []string{"StartTime must be before EndTime, got 2025-06-26T00:00:00Z and 2025-06-25T00:00:00Z"},
+			suggestion: "StartTime=2025-06-25T00:00:00Z, EndTime=2025-06-26T00:00:00Z",
+		},
+		{
+			name:       "in the future",
+			start:      "2025-06-26T00:00:00Z",
+			end:        "2025-06-27T00:00:00Z",
+			wantErrs:   []string{"EndTime must not be in the future, got 2025-06-27T00:00:00Z which is 11h after now (2025-06-26T12:30:15Z)"},
+			suggestion: "StartTime=2025-06-25T12:30:15Z, EndTime=2025-06-26T12:30:15Z",
+		},
+		{
+			name:       "too long to shift",
+			start:      "2025-05-01T00:00:00Z",
+			end:        "2025-06-27T00:00:00Z",
+			wantErrs:   []string{"EndTime must be at most 24h after StartTime, got 57d"},
+			suggestion: "StartTime=2025-06-25T12:30:15Z, EndTime=2025-06-26T12:30:15Z",
+		},
+		{
+			name:  "too old",
+			start: "2025-01-01T00:00:00Z",
+			end:   "2025-01-01T12:00:00Z",
+			wantErrs: []string{
+				"StartTime must be at most 30d before now (2025-06-26T12:30:15Z), got 2025-01-01T00:00:00Z which is 177d before now",
+			},
+			suggestion: "StartTime=2025-05-27T12:31:00Z, EndTime=2025-05-28T00:31:00Z",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckConstraints(constraints, map[string]interface{}{"StartTime": tt.start, "EndTime": tt.end}, now)
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Expected error %q, got:\n%v", want, err)
+				}
+			}
+			if !strings.HasSuffix(err.Error(), "\nNearest valid window: "+tt.suggestion) {
+				t.Errorf("Expected suggestion %q, got:\n%v", tt.suggestion, err)
+			}
+		})
+	}
+}
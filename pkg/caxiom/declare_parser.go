@@ -0,0 +1,418 @@
+package caxiom
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Position is a location in the APL source. Line and Column are 1-based,
+// Offset is the 0-based byte offset.
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("line %d, column %d", p.Line, p.Column)
+}
+
+// DeclareError is a syntax error in a declare query_parameters block
+type DeclareError struct {
+	Pos     Position
+	Message string
+}
+
+func (e *DeclareError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Message)
+}
+
+// DeclaredParameter is a parameter of a declare query_parameters block
+type DeclaredParameter struct {
+	Name          string   // APL parameter name, e.g. q_entity_id
+	Type          string   // APL type, e.g. string or datetime
+	Default       string   // Default expression as written, empty if there is none
+	Annotation    string   // Template expression of the ///param= annotation, empty if not annotated
+	Pos           Position // Position of the name
+	AnnotationPos Position // Position of the ///param= comment
+
+	typeEnd      int // Offset after the type
+	defaultStart int // Offset range of the default expression
+	defaultEnd   int
+	commentStart int // Offset range of the annotation comment
+	commentEnd   int
+}
+
+// DeclareBlock is a parsed declare query_parameters block
+type DeclareBlock struct {
+	Params []DeclaredParameter
+	Start  Position // Position of the declare keyword
+	End    Position // Position after the closing parenthesis
+}
+
+// Param returns the declared parameter with the given APL name
+func (b *DeclareBlock) Param(name string) (*DeclaredParameter, bool) {
+	for i := range b.Params {
+		if b.Params[i].Name == name {
+			return &b.Params[i], true
+		}
+	}
+	return nil, false
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenComment
+	tokenOther // Numbers and punctuation, one character or literal at a time
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	start int
+	end   int
+}
+
+// lexer splits APL into tokens. It only understands as much APL as needed to
+// find the boundaries of expressions: identifiers, string literals and
+// comments. Everything else is returned one character at a time.
+type lexer struct {
+	src string
+	pos int
+}
+
+func (l *lexer) next() (token, error) {
+	// Skip whitespace
+	for l.pos < len(l.src) && strings.ContainsRune(" \t\r\n", rune(l.src[l.pos])) {
+		l.pos++
+	}
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF, start: l.pos, end: l.pos}, nil
+	}
+
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "//"):
+		end := strings.IndexByte(l.src[l.pos:], '\n')
+		if end < 0 {
+			l.pos = len(l.src)
+		} else {
+			l.pos += end
+		}
+		return token{kind: tokenComment, text: l.src[start:l.pos], start: start, end: l.pos}, nil
+	case c == '\'' || c == '"':
+		return l.string(start, true)
+	case c == '@' && l.pos+1 < len(l.src) && (l.src[l.pos+1] == '\'' || l.src[l.pos+1] == '"'):
+		// Verbatim string, backslashes are not escapes
+		l.pos++
+		return l.string(start, false)
+	case isIdentStart(c):
+		for l.pos < len(l.src) && isIdentPart(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokenIdent, text: l.src[start:l.pos], start: start, end: l.pos}, nil
+	default:
+		l.pos++
+		return token{kind: tokenOther, text: l.src[start:l.pos], start: start, end: l.pos}, nil
+	}
+}
+
+// string scans a string literal whose opening quote is at l.pos
+func (l *lexer) string(start int, escapes bool) (token, error) {
+	quote := l.src[l.pos]
+	l.pos++
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == '\\' && escapes:
+			l.pos += 2
+		case c == quote:
+			l.pos++
+			return token{kind: tokenString, text: l.src[start:l.pos], start: start, end: l.pos}, nil
+		case c == '\n':
+			return token{}, &DeclareError{Pos: position(l.src, start), Message: "unterminated string literal"}
+		default:
+			l.pos++
+		}
+	}
+	return token{}, &DeclareError{Pos: position(l.src, start), Message: "unterminated string literal"}
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || c >= '0' && c <= '9'
+}
+
+// position converts a byte offset into a Position
+func position(src string, offset int) Position {
+	before := src[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := offset - strings.LastIndexByte(before, '\n')
+	return Position{Offset: offset, Line: line, Column: column}
+}
+
+// parseAnnotation returns the template expression of a ///param= comment,
+// allowing spaces around "param" and "=". The comma separating declarations
+// is often written after the annotation and is not part of it.
+func parseAnnotation(comment string) (string, bool) {
+	rest, ok := strings.CutPrefix(comment, "///")
+	if !ok {
+		return "", false
+	}
+	rest, ok = strings.CutPrefix(strings.TrimSpace(rest), "param")
+	if !ok {
+		return "", false
+	}
+	rest, ok = strings.CutPrefix(strings.TrimSpace(rest), "=")
+	if !ok {
+		return "", false
+	}
+	rest = strings.TrimSpace(rest)
+	rest = strings.TrimSpace(strings.TrimSuffix(rest, ","))
+	return rest, true
+}
+
+// ParseDeclareBlock parses the declare query_parameters block of an APL
+// query. It returns nil if the query has no such block.
+func ParseDeclareBlock(apl string) (*DeclareBlock, error) {
+	p := &declareParser{lex: &lexer{src: apl}, src: apl}
+	return p.parse()
+}
+
+type declareParser struct {
+	lex  *lexer
+	src  string
+	tok  token
+	prev token // Last token that wasn't a comment
+
+	// Comments seen since the last parameter, which may hold its annotation
+	comments []token
+}
+
+// advance moves to the next token that isn't a comment, collecting comments
+func (p *declareParser) advance() error {
+	if p.tok.kind != tokenComment && p.tok.end > 0 {
+		p.prev = p.tok
+	}
+	for {
+		tok, err := p.lex.next()
+		if err != nil {
+			return err
+		}
+		if tok.kind == tokenComment {
+			p.comments = append(p.comments, tok)
+			continue
+		}
+		p.tok = tok
+		return nil
+	}
+}
+
+func (p *declareParser) errorf(offset int, format string, args ...any) error {
+	return &DeclareError{Pos: position(p.src, offset), Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *declareParser) expect(text string) error {
+	if p.tok.text != text {
+		return p.errorf(p.tok.start, "expected %q, found %s", text, p.describe())
+	}
+	return p.advance()
+}
+
+func (p *declareParser) describe() string {
+	if p.tok.kind == tokenEOF {
+		return "end of query"
+	}
+	return fmt.Sprintf("%q", p.tok.text)
+}
+
+func (p *declareParser) parse() (*DeclareBlock, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	// Find "declare query_parameters" outside strings and comments
+	for {
+		if p.tok.kind == tokenEOF {
+			return nil, nil
+		}
+		if p.tok.kind == tokenIdent && p.tok.text == "declare" {
+			break
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	block := &DeclareBlock{Start: position(p.src, p.tok.start)}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.text != "query_parameters" {
+		return nil, p.errorf(p.tok.start, "expected \"query_parameters\" after declare, found %s", p.describe())
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	p.comments = nil
+
+	for p.tok.text != ")" {
+		param, err := p.parseParameter()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := block.Param(param.Name); ok {
+			return nil, p.errorf(param.Pos.Offset, "parameter %s is declared twice", param.Name)
+		}
+		block.Params = append(block.Params, param)
+
+		if p.tok.text == "," {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		} else if p.tok.text != ")" {
+			return nil, p.errorf(p.tok.start, "expected \",\" or \")\" after parameter %s, found %s", param.Name, p.describe())
+		}
+
+		// The annotation may be written before or after the comma, and is
+		// followed by the next parameter or the closing parenthesis
+		if err := p.attachAnnotation(&block.Params[len(block.Params)-1]); err != nil {
+			return nil, err
+		}
+	}
+	block.End = position(p.src, p.tok.end)
+	return block, nil
+}
+
+// parseParameter parses "name:type [= default]"
+func (p *declareParser) parseParameter() (DeclaredParameter, error) {
+	if p.tok.kind != tokenIdent {
+		return DeclaredParameter{}, p.errorf(p.tok.start, "expected parameter name, found %s", p.describe())
+	}
+	param := DeclaredParameter{Name: p.tok.text, Pos: position(p.src, p.tok.start)}
+	if err := p.advance(); err != nil {
+		return param, err
+	}
+	if err := p.expect(":"); err != nil {
+		return param, err
+	}
+	if p.tok.kind != tokenIdent {
+		return param, p.errorf(p.tok.start, "expected type of parameter %s, found %s", param.Name, p.describe())
+	}
+	param.Type = p.tok.text
+	param.typeEnd = p.tok.end
+	if err := p.advance(); err != nil {
+		return param, err
+	}
+
+	if p.tok.text != "=" {
+		return param, nil
+	}
+	if err := p.advance(); err != nil {
+		return param, err
+	}
+
+	// The default expression ends at a comma or closing parenthesis outside
+	// of nested parentheses, brackets and braces
+	param.defaultStart = p.tok.start
+	depth := 0
+	for {
+		switch {
+		case p.tok.kind == tokenEOF:
+			return param, p.errorf(param.defaultStart, "unterminated default value of parameter %s", param.Name)
+		case depth == 0 && (p.tok.text == "," || p.tok.text == ")"):
+			if p.tok.start == param.defaultStart {
+				return param, p.errorf(p.tok.start, "expected default value of parameter %s, found %s", param.Name, p.describe())
+			}
+			param.defaultEnd = p.prev.end
+			param.Default = p.src[param.defaultStart:param.defaultEnd]
+			return param, nil
+		case depth == 0 && p.tok.text == ":" && p.prev.kind == tokenIdent && p.prev.start > param.defaultStart:
+			// A colon outside of a call starts the next declaration
+			return param, p.errorf(p.prev.start, "expected \",\" before parameter %s", p.prev.text)
+		case p.tok.text == "(" || p.tok.text == "[" || p.tok.text == "{":
+			depth++
+		case p.tok.text == ")" || p.tok.text == "]" || p.tok.text == "}":
+			depth--
+		}
+		if err := p.advance(); err != nil {
+			return param, err
+		}
+	}
+}
+
+// attachAnnotation attaches the ///param= comment seen since the parameter
+// was parsed
+func (p *declareParser) attachAnnotation(param *DeclaredParameter) error {
+	for _, comment := range p.comments {
+		annotation, ok := parseAnnotation(comment.text)
+		if !ok {
+			continue
+		}
+		if param.Annotation != "" {
+			return p.errorf(comment.start, "parameter %s has more than one ///param= annotation", param.Name)
+		}
+		if annotation == "" {
+			return p.errorf(comment.start, "empty ///param= annotation for parameter %s", param.Name)
+		}
+		param.Annotation = annotation
+		param.commentStart = comment.start
+		param.commentEnd = comment.end
+		param.AnnotationPos = position(p.src, comment.start)
+	}
+	p.comments = nil
+	return nil
+}
+
+// renderTemplate replaces the default of every annotated parameter with its
+// annotation and removes the annotation comments
+func (b *DeclareBlock) renderTemplate(apl string) string {
+	type edit struct {
+		start, end int
+		text       string
+	}
+	var edits []edit
+	for _, param := range b.Params {
+		if param.Annotation == "" {
+			continue
+		}
+		if param.Default == "" {
+			edits = append(edits, edit{param.typeEnd, param.typeEnd, " = " + param.Annotation})
+		} else {
+			edits = append(edits, edit{param.defaultStart, param.defaultEnd, param.Annotation})
+		}
+		// Remove the comment together with the whitespace before it, unless
+		// it is inside the replaced default
+		if param.commentStart >= param.defaultStart && param.commentEnd <= param.defaultEnd {
+			continue
+		}
+		start := param.commentStart
+		for start > 0 && (apl[start-1] == ' ' || apl[start-1] == '\t') {
+			start--
+		}
+		edits = append(edits, edit{start, param.commentEnd, ""})
+	}
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].start < edits[j].start
+	})
+
+	var builder strings.Builder
+	last := 0
+	for _, e := range edits {
+		builder.WriteString(apl[last:e.start])
+		builder.WriteString(e.text)
+		last = e.end
+	}
+	builder.WriteString(apl[last:])
+	return builder.String()
+}
@@ -0,0 +1,102 @@
+package caxiom
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseDeclareBlock(t *testing.T) {
+	apl := `declare query_parameters ( // CuratedAxiomMCP
+    q_start:datetime = datetime(2025-06-25T00:00:00Z), ///param=datetime({{.StartTime}}),
+    q_ids:dynamic = dynamic(['a,b', "c)"]), /// param = dynamic({{.Ids}})
+    q_url:string = 'http://example.com/a,b', // not an annotation
+    q_limit:long = iff(true,
+        10,
+        20) ///param={{.Limit}}
+);
+['events'] | where url == q_url`
+
+	block, err := ParseDeclareBlock(apl)
+	if err != nil {
+		t.Fatalf("Failed to parse declare block: %v", err)
+	}
+
+	want := []DeclaredParameter{
+		{Name: "q_start", Type: "datetime", Default: "datetime(2025-06-25T00:00:00Z)", Annotation: "datetime({{.StartTime}})"},
+		{Name: "q_ids", Type: "dynamic", Default: `dynamic(['a,b', "c)"])`, Annotation: "dynamic({{.Ids}})"},
+		{Name: "q_url", Type: "string", Default: "'http://example.com/a,b'"},
+		{Name: "q_limit", Type: "long", Default: "iff(true,\n        10,\n        20)", Annotation: "{{.Limit}}"},
+	}
+	if len(block.Params) != len(want) {
+		t.Fatalf("Expected %d parameters, got %+v", len(want), block.Params)
+	}
+	for i, w := range want {
+		got := block.Params[i]
+		if got.Name != w.Name || got.Type != w.Type || got.Default != w.Default || got.Annotation != w.Annotation {
+			t.Errorf("Parameter %d: expected %+v, got %+v", i, w, got)
+		}
+	}
+	if pos := block.Params[1].Pos; pos.Line != 3 || pos.Column != 5 {
+		t.Errorf("Expected q_ids at line 3, column 5, got %s", pos)
+	}
+	if block.End.Line != 8 {
+		t.Errorf("Expected block to end on line 8, got %s", block.End)
+	}
+
+	wantTemplate := `declare query_parameters ( // CuratedAxiomMCP
+    q_start:datetime = datetime({{.StartTime}}),
+    q_ids:dynamic = dynamic({{.Ids}}),
+    q_url:string = 'http://example.com/a,b', // not an annotation
+    q_limit:long = {{.Limit}}
+);
+['events'] | where url == q_url`
+	if got := block.renderTemplate(apl); got != wantTemplate {
+		t.Errorf("Expected template:\n%s\ngot:\n%s", wantTemplate, got)
+	}
+}
+
+func TestParseDeclareBlockWithoutDefault(t *testing.T) {
+	apl := "declare query_parameters (q_id:string ///param='{{.Id}}'\n);"
+	block, err := ParseDeclareBlock(apl)
+	if err != nil {
+		t.Fatalf("Failed to parse declare block: %v", err)
+	}
+	if got := block.renderTemplate(apl); got != "declare query_parameters (q_id:string = '{{.Id}}'\n);" {
+		t.Errorf("Unexpected template: %q", got)
+	}
+}
+
+func TestParseDeclareBlockNone(t *testing.T) {
+	block, err := ParseDeclareBlock("['events'] // declare query_parameters (\n| where msg == 'declare'")
+	if err != nil || block != nil {
+		t.Errorf("Expected no block and no error, got %+v, %v", block, err)
+	}
+}
+
+func TestParseDeclareBlockErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		apl    string
+		line   int
+		column int
+	}{
+		{"missing type", "declare query_parameters (\n  q_id = 'x'\n);", 2, 8},
+		{"unterminated string", "declare query_parameters (\n  q_id:string = 'x\n);", 2, 17},
+		{"unterminated block", "declare query_parameters (\n  q_id:string = f(1\n", 2, 17},
+		{"missing separator", "declare query_parameters (\n  a:string = 'x'\n  b:string = 'y'\n);", 3, 3},
+		{"duplicate", "declare query_parameters (a:string, a:long);", 1, 37},
+		{"double annotation", "declare query_parameters (\n  a:string ///param='{{.A}}'\n  ///param='{{.B}}'\n);", 3, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseDeclareBlock(tt.apl)
+			var declareErr *DeclareError
+			if !errors.As(err, &declareErr) {
+				t.Fatalf("Expected DeclareError, got %v", err)
+			}
+			if declareErr.Pos.Line != tt.line || declareErr.Pos.Column != tt.column {
+				t.Errorf("Expected error at line %d, column %d, got %v", tt.line, tt.column, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package caxiom
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Example is an example call of a curated tool. Examples are shown in the
+// tool description, and the test command runs them and checks the results
+// against the expectations.
+type Example struct {
+	Description string         `yaml:"Description,omitempty"`
+	Params      map[string]any `yaml:"Params,omitempty"`  // Arguments of the call, as an agent would send them
+	MinRows     *int           `yaml:"MinRows,omitempty"` // Expected minimum number of rows
+	MaxRows     *int           `yaml:"MaxRows,omitempty"` // Expected maximum number of rows
+	Columns     []string       `yaml:"Columns,omitempty"` // Columns the result must have
+}
+
+// String describes the example for the tool description, e.g.
+// {"EntityId":"abc","StartTime":"now-24h"} (one day of events)
+func (e Example) String() string {
+	params := e.Params
+	if params == nil {
+		params = map[string]any{}
+	}
+	text, err := json.Marshal(params)
+	if err != nil {
+		text = []byte(fmt.Sprint(params))
+	}
+	if e.Description != "" {
+		return fmt.Sprintf("%s (%s)", text, e.Description)
+	}
+	return string(text)
+}
+
+// Check checks the columns and number of rows of a result against the
+// expectations of the example
+func (e Example) Check(columns []string, rows int) error {
+	var problems []string
+	if e.MinRows != nil && rows < *e.MinRows {
+		problems = append(problems, fmt.Sprintf("expected at least %d rows, got %d", *e.MinRows, rows))
+	}
+	if e.MaxRows != nil && rows > *e.MaxRows {
+		problems = append(problems, fmt.Sprintf("expected at most %d rows, got %d", *e.MaxRows, rows))
+	}
+	var missing []string
+	for _, column := range e.Columns {
+		if !slices.Contains(columns, column) {
+			missing = append(missing, column)
+		}
+	}
+	if len(missing) > 0 {
+		problems = append(problems, fmt.Sprintf("missing columns %s, got %s", strings.Join(missing, ", "), strings.Join(columns, ", ")))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// validateExamples checks that the examples only use known parameters, give
+// every required one, and have valid values and expectations. Values are
+// normalized to the JSON types of tool arguments, so YAML timestamps become
+// strings and integers become numbers.
+func validateExamples(examples []Example, params []ParameterDefinition) error {
+	for i := range examples {
+		if err := validateExample(&examples[i], params); err != nil {
+			return fmt.Errorf("example %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// validateExample checks and normalizes a single example
+func validateExample(example *Example, params []ParameterDefinition) error {
+	for name, value := range example.Params {
+		if t, ok := value.(time.Time); ok {
+			example.Params[name] = t.UTC().Format(time.RFC3339Nano)
+		}
+	}
+	data, err := json.Marshal(example.Params)
+	if err != nil {
+		return fmt.Errorf("invalid Params: %w", err)
+	}
+	var normalized map[string]any
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return fmt.Errorf("invalid Params: %w", err)
+	}
+	if len(normalized) > 0 {
+		example.Params = normalized
+	}
+
+	known := make(map[string]bool, len(params))
+	for _, param := range params {
+		known[param.Name] = true
+		value, ok := example.Params[param.Name]
+		if !ok || value == nil {
+			if param.IsRequired() {
+				return fmt.Errorf("parameter %s: is required", param.Name)
+			}
+			continue
+		}
+
+		paramType, _ := ParseParamType(param.Type)
+		if paramType.IsList() {
+			items, err := CoerceList(paramType, value, time.Now())
+			if err == nil {
+				err = param.CheckList(items)
+			}
+			if err != nil {
+				return fmt.Errorf("parameter %s: %w", param.Name, err)
+			}
+			continue
+		}
+		coerced, err := CoerceArgument(paramType, value, time.Now())
+		if err == nil {
+			err = param.Check(coerced)
+		}
+		if err != nil {
+			return fmt.Errorf("parameter %s: %w", param.Name, err)
+		}
+	}
+	for name := range example.Params {
+		if !known[name] {
+			return fmt.Errorf("unknown parameter %s", name)
+		}
+	}
+
+	if (example.MinRows != nil && *example.MinRows < 0) || (example.MaxRows != nil && *example.MaxRows < 0) {
+		return fmt.Errorf("MinRows and MaxRows must not be negative")
+	}
+	if example.MinRows != nil && example.MaxRows != nil && *example.MinRows > *example.MaxRows {
+		return fmt.Errorf("MinRows %d is greater than MaxRows %d", *example.MinRows, *example.MaxRows)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package caxiom
+
+import (
+	"strings"
+	"testing"
+)
+
+const examplesMetadata = "['events'] | where id == '{{.EntityId}}' and _time > datetime({{.StartTime}}) | take {{.Limit}}\n\n" +
+	"// CuratedAxiomMCP:\n" +
+	"//   Params:\n" +
+	"//     - Name: EntityId\n" +
+	"//     - Name: StartTime\n" +
+	"//       Type: datetime\n" +
+	"//     - Name: Limit\n" +
+	"//       Type: int\n" +
+	"//       Max: 100\n" +
+	"//       Required: false\n" +
+	"//       Default: \"10\"\n" +
+	"//   Examples:\n"
+
+func TestParseStarredQueryExamples(t *testing.T) {
+	apl := examplesMetadata +
+		"//     - Description: one day\n" +
+		"//       Params: {EntityId: abc, StartTime: 2025-06-25T00:00:00Z, Limit: 5}\n" +
+		"//       MinRows: 1\n" +
+		"//       Columns: [_time, id]\n" +
+		"//     - Params: {EntityId: abc, StartTime: now-1h}"
+
+	parsed, err := ParseStarredQuery("test-query", apl)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+	examples := parsed.Metadata.CuratedAxiomMCP.Examples
+	if len(examples) != 2 {
+		t.Fatalf("Expected 2 examples, got %+v", examples)
+	}
+	// Values are normalized to the JSON types of tool arguments
+	if want := `{"EntityId":"abc","Limit":5,"StartTime":"2025-06-25T00:00:00Z"} (one day)`; examples[0].String() != want {
+		t.Errorf("Expected %s, got %s", want, examples[0].String())
+	}
+	if _, ok := examples[0].Params["Limit"].(float64); !ok {
+		t.Errorf("Expected Limit to be a JSON number, got %T", examples[0].Params["Limit"])
+	}
+}
+
+func TestParseStarredQueryInvalidExamples(t *testing.T) {
+	tests := map[string]string{
+		"//     - Params: {StartTime: now}":                                                          "example 1: parameter EntityId: is required",
+		"//     - Params: {EntityId: abc, StartTime: now, Region: eu}":                               "example 1: unknown parameter Region",
+		"//     - Params: {EntityId: abc, StartTime: later}":                                         "example 1: parameter StartTime: must be an RFC 3339 date-time",
+		"//     - Params: {EntityId: abc, StartTime: now, Limit: 500}":                               "example 1: parameter Limit: must be at most 100",
+		"//     - Params: {EntityId: abc, StartTime: now}\n//       MinRows: 5\n//       MaxRows: 1": "example 1: MinRows 5 is greater than MaxRows 1",
+	}
+	for example, wantErr := range tests {
+		if _, err := ParseStarredQuery("test-query", examplesMetadata+example); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("Expected error %q for %q, got %v", wantErr, example, err)
+		}
+	}
+}
+
+func TestExampleCheck(t *testing.T) {
+	one, ten := 1, 10
+	example := Example{MinRows: &one, MaxRows: &ten, Columns: []string{"_time", "id"}}
+
+	if err := example.Check([]string{"_time", "id", "msg"}, 5); err != nil {
+		t.Errorf("Expected result to pass, got %v", err)
+	}
+	if err := example.Check([]string{"_time"}, 0); err == nil || err.Error() != "expected at least 1 rows, got 0; missing columns id, got _time" {
+		t.Errorf("Expected row count and column errors, got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package caxiom
+
+import (
+	_ "embed"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MetadataVersion is the latest version of the CuratedAxiomMCP metadata.
+// Metadata with a Version is decoded strictly, so unknown and misspelled
+// fields are errors. Metadata without one is decoded as before versions
+// existed, and unknown fields are only warnings.
+const MetadataVersion = 1
+
+// MetadataSchema is the JSON Schema of the CuratedAxiomMCP metadata, for
+// validating metadata in editors
+//
+//go:embed metadata.schema.json
+var MetadataSchema []byte
+
+// fieldProblem is an unknown field in the metadata
+type fieldProblem struct {
+	line    int // Line in the YAML
+	message string
+}
+
+// unknownFields returns the fields of a YAML node that the type doesn't
+// have, recursively. The path names the enclosing field in messages.
+func unknownFields(node *yaml.Node, t reflect.Type, path string) []fieldProblem {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var problems []fieldProblem
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			problems = append(problems, unknownFields(child, t, path)...)
+		}
+	case yaml.SequenceNode:
+		if t.Kind() == reflect.Slice {
+			for _, child := range node.Content {
+				problems = append(problems, unknownFields(child, t.Elem(), path)...)
+			}
+		}
+	case yaml.MappingNode:
+		if t.Kind() != reflect.Struct {
+			return nil
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			field, ok := fields[key.Value]
+			if !ok {
+				message := fmt.Sprintf("unknown field %s in %s", key.Value, path)
+				if suggestion := suggestField(key.Value, fields); suggestion != "" {
+					message += fmt.Sprintf(", did you mean %s?", suggestion)
+				}
+				problems = append(problems, fieldProblem{key.Line, message})
+				continue
+			}
+			problems = append(problems, unknownFields(value, field.Type, key.Value)...)
+		}
+	}
+	return problems
+}
+
+// yamlFields returns the fields of a struct by their YAML name, including the
+// fields of inlined structs
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+		if slices.Contains(strings.Split(options, ","), "inline") {
+			for inlineName, inlineField := range yamlFields(field.Type) {
+				fields[inlineName] = inlineField
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// suggestField returns the field that an unknown field is likely a misspelling
+// of: one that differs only in case, or that one of them is a prefix of
+func suggestField(name string, fields map[string]reflect.StructField) string {
+	lower := strings.ToLower(name)
+	var names []string
+	for known := range fields {
+		names = append(names, known)
+	}
+	slices.Sort(names)
+	for _, known := range names {
+		knownLower := strings.ToLower(known)
+		if knownLower == lower || (len(lower) >= 3 && (strings.HasPrefix(knownLower, lower) || strings.HasPrefix(lower, knownLower))) {
+			return known
+		}
+	}
+	return ""
+}
+
+// yamlLineRegex matches the line numbers in errors of the YAML decoder
+var yamlLineRegex = regexp.MustCompile(`\bline (\d+)`)
+
+// mapLines replaces line numbers of the YAML in an error message with the
+// line numbers in the APL
+func mapLines(message string, lineNumbers []int) string {
+	return yamlLineRegex.ReplaceAllStringFunc(message, func(match string) string {
+		n, err := strconv.Atoi(strings.TrimPrefix(match, "line "))
+		if err != nil || n < 1 || n > len(lineNumbers) {
+			return match
+		}
+		return "line " + strconv.Itoa(lineNumbers[n-1])
+	})
+}
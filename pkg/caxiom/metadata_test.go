@@ -0,0 +1,125 @@
+package caxiom
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const misspelledMetadata = `['events']
+| take 10
+
+// CuratedAxiomMCP:
+//   toolname: events
+//   Param:
+//     - Name: Limit
+//   Params:
+//     - Name: StartTime
+//       Type: date-time
+//       Exmaple: now
+//   Constraints:
+//     - Kind: not-in-future
+//       Param: StartTime
+//       Reason: x`
+
+func TestParseStarredQueryStrictMetadata(t *testing.T) {
+	apl := strings.Replace(misspelledMetadata, "// CuratedAxiomMCP:\n", "// CuratedAxiomMCP:\n//   Version: 1\n", 1)
+
+	_, err := ParseStarredQuery("test-query", apl)
+	if err == nil {
+		t.Fatal("Expected an error for unknown fields")
+	}
+	for _, want := range []string{
+		"line 6: unknown field toolname in CuratedAxiomMCP, did you mean ToolName?",
+		"line 7: unknown field Param in CuratedAxiomMCP, did you mean Params?",
+		"line 12: unknown field Exmaple in Params\n",
+		"line 16: unknown field Reason in Constraints",
+	} {
+		if !strings.Contains(err.Error()+"\n", want) {
+			t.Errorf("Expected error to contain %q, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestParseStarredQueryLegacyMetadata(t *testing.T) {
+	parsed, err := ParseStarredQuery("test-query", misspelledMetadata)
+	if err != nil {
+		t.Fatalf("Expected metadata without a Version to parse, got %v", err)
+	}
+	if parsed.Metadata.CuratedAxiomMCP.ToolName != "" || len(parsed.Metadata.CuratedAxiomMCP.Params) != 1 {
+		t.Errorf("Expected unknown fields to be ignored, got %+v", parsed.Metadata.CuratedAxiomMCP)
+	}
+	if len(parsed.Warnings) != 4 || parsed.Warnings[0] != "line 5: unknown field toolname in CuratedAxiomMCP, did you mean ToolName?" {
+		t.Errorf("Unexpected warnings: %q", parsed.Warnings)
+	}
+}
+
+func TestParseStarredQueryMetadataErrors(t *testing.T) {
+	tests := map[string]string{
+		"// CuratedAxiomMCP:\n//   Version: 2":                           "unsupported metadata Version 2, the latest version is 1",
+		"// CuratedAxiomMCP:\n//   ToolName: a\n//    Description: b":    "line 5: mapping values are not allowed in this context",
+		"// CuratedAxiomMCP:\n//   Version: 1\n//   Params: {Name: x}":   "line 5: cannot unmarshal !!map into []caxiom.ParameterDefinition",
+		"// CuratedAxiomMCP:\n//   Version: 1\n// Other: x":              "line 5: unknown field Other in the metadata",
+		"// CuratedAxiomMCP:\n//   Version: 1\n//   Timeout: 30 seconds": "line 5: cannot unmarshal !!str `30 seconds` into time.Duration",
+	}
+	for metadata, wantErr := range tests {
+		_, err := ParseStarredQuery("test-query", "['events']\n\n"+metadata)
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("Expected error %q for %q, got %v", wantErr, metadata, err)
+		}
+	}
+}
+
+func TestExtractYAMLMetadataBlankLines(t *testing.T) {
+	apl := "['events']\n\n// CuratedAxiomMCP:\n//   Version: 1\n//   ToolName: events\n\n//   Description: Recent events\n//\n//   Timeout: 30s\n\n// Not metadata"
+
+	metadata, warnings, err := extractYAMLMetadata(apl)
+	if err != nil {
+		t.Fatalf("Failed to extract metadata: %v", err)
+	}
+	if metadata.CuratedAxiomMCP.Description != "Recent events" || metadata.CuratedAxiomMCP.Timeout.String() != "30s" || len(warnings) != 0 {
+		t.Errorf("Unexpected metadata %+v, warnings %q", metadata.CuratedAxiomMCP, warnings)
+	}
+}
+
+func TestMetadataSchemaMatchesTypes(t *testing.T) {
+	var schema struct {
+		Properties struct {
+			CuratedAxiomMCP struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+			} `json:"CuratedAxiomMCP"`
+		} `json:"properties"`
+		Defs map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(MetadataSchema, &schema); err != nil {
+		t.Fatalf("Invalid schema: %v", err)
+	}
+
+	tests := map[string]struct {
+		properties map[string]json.RawMessage
+		t          reflect.Type
+	}{
+		"CuratedAxiomMCP": {schema.Properties.CuratedAxiomMCP.Properties, reflect.TypeOf(CuratedAxiomMCPConfig{})},
+		"param":           {schema.Defs["param"].Properties, reflect.TypeOf(ParameterDefinition{})},
+		"constraint":      {schema.Defs["constraint"].Properties, reflect.TypeOf(Constraint{})},
+		"example":         {schema.Defs["example"].Properties, reflect.TypeOf(Example{})},
+	}
+	for name, tt := range tests {
+		var want, got []string
+		for field := range yamlFields(tt.t) {
+			want = append(want, field)
+		}
+		for property := range tt.properties {
+			got = append(got, property)
+		}
+		slices.Sort(want)
+		slices.Sort(got)
+		if !slices.Equal(want, got) {
+			t.Errorf("Schema of %s has properties %v, expected %v", name, got, want)
+		}
+	}
+}
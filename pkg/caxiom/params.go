@@ -0,0 +1,505 @@
+package caxiom
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ParamType is the canonical type of a curated query parameter. It determines
+// the JSON Schema of the tool argument and how the argument is validated.
+type ParamType string
+
+const (
+	ParamString   ParamType = "string"
+	ParamInteger  ParamType = "integer"
+	ParamNumber   ParamType = "number"
+	ParamBoolean  ParamType = "boolean"
+	ParamDateTime ParamType = "date-time"
+	ParamDuration ParamType = "duration"
+)
+
+// paramTypeAliases maps the type names accepted in metadata to their
+// canonical type. APL type names are accepted as well.
+var paramTypeAliases = map[string]ParamType{
+	"":          ParamString,
+	"string":    ParamString,
+	"int":       ParamInteger,
+	"integer":   ParamInteger,
+	"long":      ParamInteger,
+	"float":     ParamNumber,
+	"number":    ParamNumber,
+	"real":      ParamNumber,
+	"double":    ParamNumber,
+	"decimal":   ParamNumber,
+	"bool":      ParamBoolean,
+	"boolean":   ParamBoolean,
+	"datetime":  ParamDateTime,
+	"date-time": ParamDateTime,
+	"duration":  ParamDuration,
+	"timespan":  ParamDuration,
+}
+
+// listSuffix marks list types, e.g. string[] or int[]
+const listSuffix = "[]"
+
+// DefaultMaxItems is the maximum number of items of list parameters without
+// a MaxItems
+const DefaultMaxItems = 100
+
+// ParseParamType returns the canonical type of a metadata type name
+func ParseParamType(name string) (ParamType, error) {
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	if elemName, ok := strings.CutSuffix(normalized, listSuffix); ok {
+		elem, err := ParseParamType(elemName)
+		if err != nil {
+			return "", err
+		}
+		if elem != ParamString && elem != ParamInteger && elem != ParamNumber {
+			return "", fmt.Errorf("unsupported list type %q, use string[], int[] or float[]", name)
+		}
+		return elem + listSuffix, nil
+	}
+
+	paramType, ok := paramTypeAliases[normalized]
+	if !ok {
+		return "", fmt.Errorf("unknown type %q, use one of string, int, float, bool, datetime or duration, or a list such as string[]", name)
+	}
+	return paramType, nil
+}
+
+// IsList reports whether the type is a list type such as string[]
+func (t ParamType) IsList() bool {
+	return strings.HasSuffix(string(t), listSuffix)
+}
+
+// Elem returns the item type of a list type, and the type itself otherwise
+func (t ParamType) Elem() ParamType {
+	return ParamType(strings.TrimSuffix(string(t), listSuffix))
+}
+
+// timespanRegex matches APL timespan literals such as 7d, 1.5h or 100ms
+var timespanRegex = regexp.MustCompile(`^(\d+(?:\.\d+)?)(d|h|m|s|ms|microsecond|tick)$`)
+
+// isoDurationRegex matches ISO 8601 durations in days and time, such as P7D or PT1H30M
+var isoDurationRegex = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// timespanUnits are the APL timespan units used to format durations, largest first
+var timespanUnits = []struct {
+	suffix string
+	unit   time.Duration
+}{
+	{"d", 24 * time.Hour},
+	{"h", time.Hour},
+	{"m", time.Minute},
+	{"s", time.Second},
+	{"ms", time.Millisecond},
+}
+
+// CoerceArgument validates a tool argument of a curated parameter and returns
+// the canonical text that is rendered into the query template. Arguments may
+// be given as JSON values of the parameter type or as strings, since agents
+// often quote numbers and booleans. Relative date-times such as now-24h are
+// resolved against now. Errors describe what is wrong with the value, without
+// the parameter name.
+func CoerceArgument(paramType ParamType, value any, now time.Time) (string, error) {
+	text, isString := value.(string)
+	if isString {
+		text = strings.TrimSpace(text)
+	}
+
+	switch paramType {
+	case ParamString:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		}
+		return "", fmt.Errorf("must be a string, got %s", describeValue(value))
+
+	case ParamInteger:
+		switch v := value.(type) {
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+				return strconv.FormatInt(int64(v), 10), nil
+			}
+		case string:
+			if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+				return strconv.FormatInt(n, 10), nil
+			}
+		}
+		return "", fmt.Errorf("must be an integer, got %s", describeValue(value))
+
+	case ParamNumber:
+		switch v := value.(type) {
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case string:
+			if f, err := strconv.ParseFloat(text, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+				return strconv.FormatFloat(f, 'f', -1, 64), nil
+			}
+		}
+		return "", fmt.Errorf("must be a number, got %s", describeValue(value))
+
+	case ParamBoolean:
+		switch v := value.(type) {
+		case bool:
+			return strconv.FormatBool(v), nil
+		case string:
+			if b, err := strconv.ParseBool(text); err == nil {
+				return strconv.FormatBool(b), nil
+			}
+		}
+		return "", fmt.Errorf("must be true or false, got %s", describeValue(value))
+
+	case ParamDateTime:
+		if isString {
+			if t, err := ParseDateTime(text, now); err == nil {
+				return t.Format(time.RFC3339Nano), nil
+			}
+		}
+		return "", fmt.Errorf("must be an RFC 3339 date-time such as 2025-06-25T00:00:00Z, or a relative time such as now, now-24h, today, yesterday or start of week, got %s", describeValue(value))
+
+	case ParamDuration:
+		if isString {
+			if d, ok := parseDuration(text); ok {
+				return FormatTimespan(d), nil
+			}
+		}
+		return "", fmt.Errorf("must be a duration such as 30m, 24h, 7d or PT1H, got %s", describeValue(value))
+	}
+
+	return "", fmt.Errorf("has unsupported type %q", paramType)
+}
+
+// CoerceList validates a list argument and returns the canonical text of every
+// item. Lists may be given as JSON arrays, or as strings containing a JSON
+// array. Errors name the offending item.
+func CoerceList(paramType ParamType, value any, now time.Time) ([]string, error) {
+	if text, ok := value.(string); ok {
+		var decoded []any
+		if err := json.Unmarshal([]byte(text), &decoded); err != nil {
+			return nil, fmt.Errorf("must be a list such as [\"a\", \"b\"], got %s", describeValue(value))
+		}
+		value = decoded
+	}
+	values, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("must be a list such as [\"a\", \"b\"], got %s", describeValue(value))
+	}
+
+	items := make([]string, len(values))
+	for i, v := range values {
+		item, err := CoerceArgument(paramType.Elem(), v, now)
+		if err != nil {
+			return nil, fmt.Errorf("item %d %w", i+1, err)
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+// ParamConstraints restrict the values of a curated parameter beyond its type.
+// They are advertised in the tool schema and checked before a query runs.
+type ParamConstraints struct {
+	Enum      []string `yaml:"Enum,omitempty"`      // Allowed values
+	Pattern   string   `yaml:"Pattern,omitempty"`   // Regular expression that string values must match
+	Min       *float64 `yaml:"Min,omitempty"`       // Inclusive lower bound of numbers
+	Max       *float64 `yaml:"Max,omitempty"`       // Inclusive upper bound of numbers
+	MaxLength int      `yaml:"MaxLength,omitempty"` // Maximum number of characters of strings
+	MaxItems  int      `yaml:"MaxItems,omitempty"`  // Maximum number of items of lists, defaults to DefaultMaxItems
+
+	pattern *regexp.Regexp // Compiled Pattern, set by validate
+}
+
+// ItemLimit returns the maximum number of items of a list parameter
+func (c ParamConstraints) ItemLimit() int {
+	if c.MaxItems > 0 {
+		return c.MaxItems
+	}
+	return DefaultMaxItems
+}
+
+// validate checks that the constraints apply to the parameter type, and
+// rewrites the allowed values in canonical form so they compare with coerced
+// arguments. Constraints of list parameters other than MaxItems apply to
+// every item.
+func (c *ParamConstraints) validate(paramType ParamType) error {
+	if c.MaxItems != 0 && !paramType.IsList() {
+		return fmt.Errorf("MaxItems only applies to list parameters")
+	}
+	if c.MaxItems < 0 {
+		return fmt.Errorf("MaxItems must not be negative")
+	}
+	paramType = paramType.Elem()
+
+	for i, value := range c.Enum {
+		canonical, err := CoerceArgument(paramType, value, time.Now())
+		if err != nil {
+			return fmt.Errorf("invalid Enum value: %w", err)
+		}
+		c.Enum[i] = canonical
+	}
+	if c.Pattern != "" {
+		if paramType != ParamString {
+			return fmt.Errorf("Pattern only applies to string parameters")
+		}
+		pattern, err := regexp.Compile(c.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid Pattern: %w", err)
+		}
+		c.pattern = pattern
+	}
+	if c.MaxLength != 0 && paramType != ParamString {
+		return fmt.Errorf("MaxLength only applies to string parameters")
+	}
+	if c.MaxLength < 0 {
+		return fmt.Errorf("MaxLength must not be negative")
+	}
+	if c.Min != nil || c.Max != nil {
+		if paramType != ParamInteger && paramType != ParamNumber {
+			return fmt.Errorf("Min and Max only apply to int and float parameters")
+		}
+		if c.Min != nil && c.Max != nil && *c.Min > *c.Max {
+			return fmt.Errorf("Min %v is greater than Max %v", *c.Min, *c.Max)
+		}
+	}
+	return nil
+}
+
+// Check reports whether a coerced argument satisfies the constraints, which
+// must have been validated. Errors describe the allowed values, without the
+// parameter name.
+func (c ParamConstraints) Check(value string) error {
+	if len(c.Enum) > 0 && !slices.Contains(c.Enum, value) {
+		allowed := make([]string, len(c.Enum))
+		for i, v := range c.Enum {
+			allowed[i] = strconv.Quote(v)
+		}
+		return fmt.Errorf("must be one of %s, got %q", strings.Join(allowed, ", "), value)
+	}
+	if c.pattern != nil {
+		if !c.pattern.MatchString(value) {
+			return fmt.Errorf("must match the pattern %s, got %q", c.Pattern, value)
+		}
+	}
+	if c.MaxLength > 0 {
+		if length := utf8.RuneCountInString(value); length > c.MaxLength {
+			return fmt.Errorf("must be at most %d characters long, got %d characters", c.MaxLength, length)
+		}
+	}
+	if c.Min != nil || c.Max != nil {
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("must be a number, got %q", value)
+		}
+		if c.Min != nil && number < *c.Min {
+			return fmt.Errorf("must be at least %s, got %s", formatBound(*c.Min), value)
+		}
+		if c.Max != nil && number > *c.Max {
+			return fmt.Errorf("must be at most %s, got %s", formatBound(*c.Max), value)
+		}
+	}
+	return nil
+}
+
+// CheckList reports whether a coerced list argument has at most ItemLimit
+// items, and every item satisfies the constraints
+func (c ParamConstraints) CheckList(items []string) error {
+	if len(items) > c.ItemLimit() {
+		return fmt.Errorf("must have at most %d items, got %d", c.ItemLimit(), len(items))
+	}
+	for i, item := range items {
+		if err := c.Check(item); err != nil {
+			return fmt.Errorf("item %d %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// formatBound formats a Min or Max bound for error messages
+func formatBound(bound float64) string {
+	return strconv.FormatFloat(bound, 'f', -1, 64)
+}
+
+// parseDuration parses an APL timespan such as 7d, an ISO 8601 duration such
+// as PT1H or a Go duration such as 1h30m
+func parseDuration(text string) (time.Duration, bool) {
+	if match := timespanRegex.FindStringSubmatch(text); match != nil {
+		value, err := strconv.ParseFloat(match[1], 64)
+		if err != nil {
+			return 0, false
+		}
+		unit := map[string]time.Duration{
+			"d": 24 * time.Hour, "h": time.Hour, "m": time.Minute, "s": time.Second,
+			"ms": time.Millisecond, "microsecond": time.Microsecond, "tick": 100 * time.Nanosecond,
+		}[match[2]]
+		return time.Duration(value * float64(unit)), true
+	}
+	if match := isoDurationRegex.FindStringSubmatch(text); match != nil && text != "P" && !strings.HasSuffix(text, "T") {
+		var d time.Duration
+		for i, unit := range []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second} {
+			if match[i+1] != "" {
+				value, err := strconv.ParseFloat(match[i+1], 64)
+				if err != nil {
+					return 0, false
+				}
+				d += time.Duration(value * float64(unit))
+			}
+		}
+		return d, true
+	}
+	if d, err := time.ParseDuration(text); err == nil && d >= 0 {
+		return d, true
+	}
+	return 0, false
+}
+
+// FormatTimespan formats a duration as an APL timespan literal in the
+// largest unit that represents it exactly, e.g. 2d, 90m or 1500ms
+func FormatTimespan(d time.Duration) string {
+	for _, u := range timespanUnits {
+		if d%u.unit == 0 {
+			return strconv.FormatInt(int64(d/u.unit), 10) + u.suffix
+		}
+	}
+	return strconv.FormatInt(int64(d/time.Microsecond), 10) + "microsecond"
+}
+
+// describeValue describes an argument for error messages
+func describeValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(v)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprintf("a %T", value)
+	}
+}
+
+// placeholderRegex matches a template placeholder such as {{.EntityId}}
+var placeholderRegex = regexp.MustCompile(`\{\{-?\s*\.(\w+)\s*-?\}\}`)
+
+// resolveParams checks the type of every parameter, and the default value of
+// optional parameters. Optional parameters without a Default take the value
+// of the declare block default they are annotated on, and lists default to
+// the empty list. Optional parameters of optional sections, named in
+// conditional, need no default.
+func resolveParams(params []ParameterDefinition, declared []DeclaredParameter, conditional map[string]bool) error {
+	for i := range params {
+		param := &params[i]
+		paramType, err := ParseParamType(param.Type)
+		if err != nil {
+			return fmt.Errorf("parameter %s: %w", param.Name, err)
+		}
+		if err := param.ParamConstraints.validate(paramType); err != nil {
+			return fmt.Errorf("parameter %s: %w", param.Name, err)
+		}
+		if param.IsRequired() {
+			continue
+		}
+
+		if param.Default == "" && paramType.IsList() {
+			param.Default = "[]"
+		}
+		if param.Default == "" {
+			value, ok := declaredDefault(param.Name, declared)
+			if !ok && conditional[param.Name] {
+				continue
+			}
+			if !ok {
+				return fmt.Errorf("parameter %s: optional parameters need a Default, an annotated default in declare query_parameters, or an optional section", param.Name)
+			}
+			param.Default = value
+		}
+		if paramType.IsList() {
+			items, err := CoerceList(paramType, param.Default, time.Now())
+			if err == nil {
+				err = param.CheckList(items)
+			}
+			if err != nil {
+				return fmt.Errorf("parameter %s: invalid Default: %w", param.Name, err)
+			}
+			continue
+		}
+		value, err := CoerceArgument(paramType, param.Default, time.Now())
+		if err == nil {
+			err = param.Check(value)
+		}
+		if err != nil {
+			return fmt.Errorf("parameter %s: invalid Default: %w", param.Name, err)
+		}
+	}
+	return nil
+}
+
+// declaredDefault derives the value of a parameter from the default of the
+// first declared parameter annotated with it whose default matches the
+// annotation, so an annotation datetime({{.Start}}) and a default
+// datetime(2025-06-25T00:00:00Z) give 2025-06-25T00:00:00Z.
+func declaredDefault(name string, declared []DeclaredParameter) (string, bool) {
+	for _, dp := range declared {
+		matches := placeholderRegex.FindAllStringSubmatchIndex(dp.Annotation, -1)
+		if len(matches) != 1 || dp.Annotation[matches[0][2]:matches[0][3]] != name || dp.Default == "" {
+			continue
+		}
+		prefix := dp.Annotation[:matches[0][0]]
+		suffix := dp.Annotation[matches[0][1]:]
+		if len(dp.Default) < len(prefix)+len(suffix) ||
+			!strings.HasPrefix(dp.Default, prefix) || !strings.HasSuffix(dp.Default, suffix) {
+			continue
+		}
+		value := dp.Default[len(prefix) : len(dp.Default)-len(suffix)]
+
+		// Values inside string literals are escaped
+		if strings.HasSuffix(prefix, "'") || strings.HasSuffix(prefix, `"`) {
+			unescaped, ok := unescapeString(value)
+			if !ok {
+				continue
+			}
+			value = unescaped
+		}
+		return value, true
+	}
+	return "", false
+}
+
+// unescapeString reverses EscapeString
+func unescapeString(value string) (string, bool) {
+	var builder strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' {
+			builder.WriteByte(value[i])
+			continue
+		}
+		i++
+		if i == len(value) {
+			return "", false
+		}
+		switch value[i] {
+		case 'n':
+			builder.WriteByte('\n')
+		case 'r':
+			builder.WriteByte('\r')
+		case 't':
+			builder.WriteByte('\t')
+		default:
+			builder.WriteByte(value[i])
+		}
+	}
+	return builder.String(), true
+}
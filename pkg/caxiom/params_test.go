@@ -0,0 +1,335 @@
+package caxiom
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseParamType(t *testing.T) {
+	tests := map[string]ParamType{
+		"":          ParamString,
+		"string":    ParamString,
+		"int":       ParamInteger,
+		"long":      ParamInteger,
+		"float":     ParamNumber,
+		"Bool":      ParamBoolean,
+		"datetime":  ParamDateTime,
+		"date-time": ParamDateTime,
+		"timespan":  ParamDuration,
+	}
+	for name, want := range tests {
+		got, err := ParseParamType(name)
+		if err != nil || got != want {
+			t.Errorf("ParseParamType(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+	if _, err := ParseParamType("datetim"); err == nil {
+		t.Error("Expected error for unknown type")
+	}
+}
+
+func TestCoerceArgument(t *testing.T) {
+	now := time.Date(2025, 6, 26, 12, 30, 15, 0, time.UTC)
+	tests := []struct {
+		paramType ParamType
+		value     any
+		want      string
+		wantErr   string
+	}{
+		{ParamString, "abc", "abc", ""},
+		{ParamString, float64(12345), "12345", ""},
+		{ParamString, []any{"a"}, "", "must be a string, got a []interface {}"},
+		{ParamInteger, float64(42), "42", ""},
+		{ParamInteger, " 42 ", "42", ""},
+		{ParamInteger, 4.5, "", "must be an integer, got 4.5"},
+		{ParamInteger, "ten", "", `must be an integer, got "ten"`},
+		{ParamNumber, 0.25, "0.25", ""},
+		{ParamNumber, "1e3", "1000", ""},
+		{ParamNumber, "NaN", "", `must be a number, got "NaN"`},
+		{ParamBoolean, true, "true", ""},
+		{ParamBoolean, "false", "false", ""},
+		{ParamBoolean, "yes", "", `must be true or false, got "yes"`},
+		{ParamDateTime, "2025-06-25T02:00:00+02:00", "2025-06-25T00:00:00Z", ""},
+		{ParamDateTime, "now-24h", "2025-06-25T12:30:00Z", ""},
+		{ParamDateTime, "2025-06-25", "", "must be an RFC 3339 date-time"},
+		{ParamDateTime, nil, "", "got null"},
+		{ParamDuration, "7d", "7d", ""},
+		{ParamDuration, "1.5h", "90m", ""},
+		{ParamDuration, "1h30m", "90m", ""},
+		{ParamDuration, "PT36H", "36h", ""},
+		{ParamDuration, "-1h", "", "must be a duration"},
+	}
+	for _, tt := range tests {
+		got, err := CoerceArgument(tt.paramType, tt.value, now)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CoerceArgument(%s, %v) error = %v, want %q", tt.paramType, tt.value, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("CoerceArgument(%s, %v) = %q, %v, want %q", tt.paramType, tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestFormatTimespan(t *testing.T) {
+	tests := map[time.Duration]string{
+		48 * time.Hour:          "2d",
+		90 * time.Minute:        "90m",
+		1500 * time.Millisecond: "1500ms",
+		0:                       "0d",
+	}
+	for d, want := range tests {
+		if got := FormatTimespan(d); got != want {
+			t.Errorf("FormatTimespan(%v) = %s, want %s", d, got, want)
+		}
+	}
+}
+
+func TestParseStarredQueryUnknownParamType(t *testing.T) {
+	apl := `['events'] | where id == '{{.Id}}'
+
+// CuratedAxiomMCP:
+//   ToolName: by_id
+//   Params:
+//     - Name: Id
+//       Type: uuid`
+
+	if _, err := ParseStarredQuery("test-query", apl); err == nil || !strings.Contains(err.Error(), "parameter Id: unknown type") {
+		t.Errorf("Expected unknown type error, got %v", err)
+	}
+}
+
+func TestParseStarredQueryOptionalParams(t *testing.T) {
+	apl := `declare query_parameters (
+    q_start:datetime = datetime(2025-06-25T02:00:00+02:00), ///param=datetime({{.StartTime}})
+    q_owner:string = 'it\'s me', ///param='{{.Owner}}'
+    q_limit:long = 10 ///param={{.Limit}}
+);
+['events'] | where _time > q_start and owner == q_owner | limit q_limit
+
+// CuratedAxiomMCP:
+//   ToolName: events
+//   Params:
+//     - Name: StartTime
+//       Type: date-time
+//       Required: false
+//     - Name: Owner
+//       Type: string
+//       Required: false
+//     - Name: Limit
+//       Type: int
+//       Required: false
+//       Default: "100"`
+
+	parsed, err := ParseStarredQuery("test-query", apl)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+
+	want := map[string]string{
+		"StartTime": "2025-06-25T02:00:00+02:00",
+		"Owner":     "it's me",
+		"Limit":     "100",
+	}
+	for _, param := range parsed.Metadata.CuratedAxiomMCP.Params {
+		if param.IsRequired() {
+			t.Errorf("Expected %s to be optional", param.Name)
+		}
+		if param.Default != want[param.Name] {
+			t.Errorf("Expected %s to default to %q, got %q", param.Name, want[param.Name], param.Default)
+		}
+	}
+}
+
+func TestParseStarredQueryOptionalParamSharedPlaceholder(t *testing.T) {
+	apl := `declare query_parameters (
+    q_from:datetime = ago(1d), ///param=datetime({{.Since}})
+    q_since:datetime = datetime(2025-06-25T00:00:00Z) ///param=datetime({{.Since}})
+);
+['events'] | where _time > q_from and _time > q_since
+
+// CuratedAxiomMCP:
+//   Params:
+//     - Name: Since
+//       Type: date-time
+//       Required: false`
+
+	parsed, err := ParseStarredQuery("test-query", apl)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+	if got := parsed.Metadata.CuratedAxiomMCP.Params[0].Default; got != "2025-06-25T00:00:00Z" {
+		t.Errorf("Expected the default of the second declaration, got %q", got)
+	}
+}
+
+func TestParseStarredQueryOptionalParamErrors(t *testing.T) {
+	tests := map[string]string{
+		"no default": `['events'] | where id == '{{.Id}}'
+
+// CuratedAxiomMCP:
+//   Params:
+//     - Name: Id
+//       Required: false`,
+		"invalid default": `['events'] | take {{.Limit}}
+
+// CuratedAxiomMCP:
+//   Params:
+//     - Name: Limit
+//       Type: int
+//       Required: false
+//       Default: ten`,
+	}
+	wantErr := map[string]string{
+		"no default":      "parameter Id: optional parameters need a Default",
+		"invalid default": `parameter Limit: invalid Default: must be an integer, got "ten"`,
+	}
+	for name, apl := range tests {
+		if _, err := ParseStarredQuery("test-query", apl); err == nil || !strings.Contains(err.Error(), wantErr[name]) {
+			t.Errorf("%s: expected error %q, got %v", name, wantErr[name], err)
+		}
+	}
+}
+
+func TestParamConstraintsCheck(t *testing.T) {
+	minimum, maximum := 1.0, 500.0
+	tests := []struct {
+		constraints ParamConstraints
+		value       string
+		wantErr     string
+	}{
+		{ParamConstraints{Enum: []string{"error", "warn"}}, "warn", ""},
+		{ParamConstraints{Enum: []string{"error", "warn"}}, "info", `must be one of "error", "warn", got "info"`},
+		{ParamConstraints{Pattern: `^[a-z0-9-]+$`}, "entity-1", ""},
+		{ParamConstraints{Pattern: `^[a-z0-9-]+$`}, "Entity 1", `must match the pattern ^[a-z0-9-]+$, got "Entity 1"`},
+		{ParamConstraints{MaxLength: 3}, "åäö", ""},
+		{ParamConstraints{MaxLength: 3}, "abcd", "must be at most 3 characters long, got 4 characters"},
+		{ParamConstraints{Min: &minimum, Max: &maximum}, "500", ""},
+		{ParamConstraints{Min: &minimum, Max: &maximum}, "0", "must be at least 1, got 0"},
+		{ParamConstraints{Min: &minimum, Max: &maximum}, "500.5", "must be at most 500, got 500.5"},
+	}
+	for _, tt := range tests {
+		paramType := ParamString
+		if tt.constraints.Min != nil {
+			paramType = ParamNumber
+		}
+		if err := tt.constraints.validate(paramType); err != nil {
+			t.Fatalf("Invalid constraints %+v: %v", tt.constraints, err)
+		}
+		err := tt.constraints.Check(tt.value)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("Check(%q) = %v, want no error", tt.value, err)
+		}
+		if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+			t.Errorf("Check(%q) = %v, want %q", tt.value, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseStarredQueryParamConstraints(t *testing.T) {
+	apl := `['events'] | where level == '{{.Level}}' | take {{.Limit}}
+
+// CuratedAxiomMCP:
+//   Params:
+//     - Name: Level
+//       Enum: [error, warn]
+//     - Name: Limit
+//       Type: int
+//       Enum: ["10", 100]
+//       Min: 1`
+
+	parsed, err := ParseStarredQuery("test-query", apl)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+	limit := parsed.Metadata.CuratedAxiomMCP.Params[1]
+	if strings.Join(limit.Enum, ",") != "10,100" || limit.Min == nil || *limit.Min != 1 {
+		t.Errorf("Expected canonical enum and Min, got %+v", limit.ParamConstraints)
+	}
+
+	invalid := map[string]string{
+		"Type: int\n//       Pattern: '^1'":                           "parameter P: Pattern only applies to string parameters",
+		"Pattern: '(['":                                               "parameter P: invalid Pattern",
+		"Type: string\n//       Min: 1":                               "parameter P: Min and Max only apply to int and float parameters",
+		"Type: int\n//       Min: 10\n//       Max: 1":                "parameter P: Min 10 is greater than Max 1",
+		"Type: int\n//       Enum: [ten]":                             `parameter P: invalid Enum value: must be an integer, got "ten"`,
+		"Required: false\n//       Default: c\n//       Enum: [a, b]": `parameter P: invalid Default: must be one of "a", "b", got "c"`,
+	}
+	for metadata, wantErr := range invalid {
+		apl := "['events'] | where p == '{{.P}}'\n\n// CuratedAxiomMCP:\n//   Params:\n//     - Name: P\n//       " + metadata
+		if _, err := ParseStarredQuery("test-query", apl); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("Expected error %q for %q, got %v", wantErr, metadata, err)
+		}
+	}
+}
+
+func TestParseParamTypeList(t *testing.T) {
+	tests := map[string]ParamType{
+		"string[]": "string[]",
+		"int[]":    "integer[]",
+		"Float[]":  "number[]",
+	}
+	for name, want := range tests {
+		got, err := ParseParamType(name)
+		if err != nil || got != want || !got.IsList() {
+			t.Errorf("ParseParamType(%q) = %q, %v, want list %q", name, got, err, want)
+		}
+	}
+	if elem := ParamType("integer[]").Elem(); elem != ParamInteger {
+		t.Errorf("Expected integer items, got %q", elem)
+	}
+	for _, name := range []string{"datetime[]", "string[][]", "uuid[]"} {
+		if _, err := ParseParamType(name); err == nil {
+			t.Errorf("Expected error for %q", name)
+		}
+	}
+}
+
+func TestCoerceList(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		paramType ParamType
+		value     any
+		want      []string
+		wantErr   string
+	}{
+		{"string[]", []any{"a", "b'c"}, []string{"a", "b'c"}, ""},
+		{"integer[]", []any{float64(1), "2"}, []string{"1", "2"}, ""},
+		{"integer[]", `[3, "4"]`, []string{"3", "4"}, ""},
+		{"string[]", []any{}, []string{}, ""},
+		{"integer[]", []any{float64(1), "x"}, nil, `item 2 must be an integer, got "x"`},
+		{"string[]", "a,b", nil, `must be a list such as ["a", "b"], got "a,b"`},
+		{"string[]", float64(1), nil, "must be a list"},
+	}
+	for _, tt := range tests {
+		got, err := CoerceList(tt.paramType, tt.value, now)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CoerceList(%s, %v) error = %v, want %q", tt.paramType, tt.value, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("CoerceList(%s, %v) = %q, %v, want %q", tt.paramType, tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestParamConstraintsCheckList(t *testing.T) {
+	constraints := ParamConstraints{Enum: []string{"a", "b"}, MaxItems: 2}
+	if err := constraints.CheckList([]string{"a", "b"}); err != nil {
+		t.Errorf("Expected valid list, got %v", err)
+	}
+	if err := constraints.CheckList([]string{"a", "b", "a"}); err == nil || err.Error() != "must have at most 2 items, got 3" {
+		t.Errorf("Expected max items error, got %v", err)
+	}
+	if err := constraints.CheckList([]string{"a", "c"}); err == nil || err.Error() != `item 2 must be one of "a", "b", got "c"` {
+		t.Errorf("Expected enum error, got %v", err)
+	}
+	if limit := (ParamConstraints{}).ItemLimit(); limit != DefaultMaxItems {
+		t.Errorf("Expected default item limit, got %d", limit)
+	}
+}
@@ -2,8 +2,9 @@ package caxiom
 
 import (
 	"fmt"
-	"regexp"
+	"reflect"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -14,6 +15,8 @@ type ParsedQuery struct {
 	OriginalAPL  string
 	TemplateAPL  string
 	Metadata     *QueryMetadata
+	Declared     []DeclaredParameter // Parameters of the declare query_parameters block
+	Warnings     []string            // Problems of metadata without a Version, which are ignored
 }
 
 // QueryMetadata represents the YAML metadata extracted from query comments
@@ -23,18 +26,30 @@ type QueryMetadata struct {
 
 // CuratedAxiomMCPConfig represents the configuration for curated axiom MCP
 type CuratedAxiomMCPConfig struct {
+	Version     int                   `yaml:"Version,omitempty"` // See MetadataVersion, 0 for metadata without a version
 	ToolName    string                `yaml:"ToolName,omitempty"`
 	Params      []ParameterDefinition `yaml:"Params,omitempty"`
-	Constraints []string              `yaml:"Constraints,omitempty"`
+	Constraints []Constraint          `yaml:"Constraints,omitempty"`
+	Examples    []Example             `yaml:"Examples,omitempty"`
 	Description string                `yaml:"Description,omitempty"`
+	Timeout     time.Duration         `yaml:"Timeout,omitempty"` // e.g. 30s, overrides queries.timeout
 }
 
 // ParameterDefinition represents a parameter definition from the YAML metadata
 type ParameterDefinition struct {
 	Name        string `yaml:"Name"`
-	Type        string `yaml:"Type"`
+	Type        string `yaml:"Type"` // See ParseParamType for the accepted types
 	Example     string `yaml:"Example,omitempty"`
 	Description string `yaml:"Description,omitempty"`
+	Required    *bool  `yaml:"Required,omitempty"` // Defaults to true
+	Default     string `yaml:"Default,omitempty"`  // Value of an optional parameter, derived from the declare block if empty
+
+	ParamConstraints `yaml:",inline"`
+}
+
+// IsRequired reports whether the parameter must be given in every call
+func (p ParameterDefinition) IsRequired() bool {
+	return p.Required == nil || *p.Required
 }
 
 // ParseStarredQuery parses a starred query's APL for MCP usage
@@ -52,15 +67,43 @@ func ParseStarredQuery(queryName, apl string) (*ParsedQuery, error) {
 	}
 
 	// Extract YAML metadata from comments
-	metadata, err := extractYAMLMetadata(apl)
+	metadata, warnings, err := extractYAMLMetadata(apl)
 	if err != nil {
 		return nil, fmt.Errorf("failed to extract YAML metadata: %w", err)
 	}
 
 	// Convert parameter declarations to template format
-	templateAPL, err := convertToTemplate(apl)
+	block, err := ParseDeclareBlock(apl)
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert to template: %w", err)
+		return nil, fmt.Errorf("failed to parse declare query_parameters: %w", err)
+	}
+	templateAPL := apl
+	var declared []DeclaredParameter
+	if block != nil {
+		templateAPL = block.renderTemplate(apl)
+		declared = block.Params
+	}
+
+	// Check the template syntax and helpers, so broken templates aren't loaded
+	if _, err := ParseTemplate(templateAPL); err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	// Check parameter types and defaults, so tools get a proper input schema
+	if err := resolveParams(metadata.CuratedAxiomMCP.Params, declared, suppliedNames(templateAPL)); err != nil {
+		return nil, err
+	}
+	if err := validateSections(templateAPL, metadata.CuratedAxiomMCP.Params); err != nil {
+		return nil, err
+	}
+	if err := validateDefaults(templateAPL, metadata.CuratedAxiomMCP.Params); err != nil {
+		return nil, err
+	}
+	if err := validateConstraints(metadata.CuratedAxiomMCP.Constraints, metadata.CuratedAxiomMCP.Params); err != nil {
+		return nil, err
+	}
+	if err := validateExamples(metadata.CuratedAxiomMCP.Examples, metadata.CuratedAxiomMCP.Params); err != nil {
+		return nil, err
 	}
 
 	return &ParsedQuery{
@@ -68,89 +111,99 @@ func ParseStarredQuery(queryName, apl string) (*ParsedQuery, error) {
 		OriginalAPL:  apl,
 		TemplateAPL:  templateAPL,
 		Metadata:     metadata,
+		Declared:     declared,
+		Warnings:     warnings,
 	}, nil
 }
 
-// extractYAMLMetadata extracts and parses YAML metadata from APL comments
-func extractYAMLMetadata(apl string) (*QueryMetadata, error) {
+// extractYAMLMetadata extracts and parses YAML metadata from APL comments.
+// It also returns warnings about metadata that is accepted for compatibility.
+func extractYAMLMetadata(apl string) (*QueryMetadata, []string, error) {
 	lines := strings.Split(apl, "\n")
 	var yamlLines []string
+	var lineNumbers []int // Line in the APL of every YAML line
 	inYAMLSection := false
 
-	for _, line := range lines {
+	for i, line := range lines {
 		trimmed := strings.TrimSpace(line)
 		
 		// Look for the start of YAML section
-		if strings.Contains(trimmed, "// CuratedAxiomMCP:") {
+		if !inYAMLSection {
+			if !strings.Contains(trimmed, "// CuratedAxiomMCP:") {
+				continue
+			}
 			inYAMLSection = true
-			// Include this line as it contains the root YAML key
-			if strings.HasPrefix(line, "// ") {
-				yamlContent := strings.TrimPrefix(line, "// ")
-				yamlLines = append(yamlLines, yamlContent)
-			} else if strings.HasPrefix(line, "//") {
-				yamlContent := strings.TrimPrefix(line, "//")
-				yamlLines = append(yamlLines, yamlContent)
+		} else if trimmed == "" {
+			// Keep blank lines between indented metadata lines
+			if continuesYAML(lines[i+1:]) {
+				yamlLines = append(yamlLines, "")
+				lineNumbers = append(lineNumbers, i+1)
+				continue
 			}
-			continue
+			break
+		} else if !strings.HasPrefix(trimmed, "//") {
+			// Stop at the first non-comment line
+			break
 		}
 
-		// If we're in the YAML section
-		if inYAMLSection {
-			// Stop if we hit an empty line or non-comment line
-			if trimmed == "" || !strings.HasPrefix(trimmed, "//") {
-				break
-			}
-
-			// Remove the comment prefix but preserve the original indentation structure
-			if strings.HasPrefix(line, "// ") {
-				yamlContent := strings.TrimPrefix(line, "// ")
-				yamlLines = append(yamlLines, yamlContent)
-			} else if strings.HasPrefix(line, "//") {
-				yamlContent := strings.TrimPrefix(line, "//")
-				yamlLines = append(yamlLines, yamlContent)
-			}
+		// Remove the comment prefix but preserve the original indentation structure
+		if yamlContent, ok := commentContent(line); ok {
+			yamlLines = append(yamlLines, yamlContent)
+			lineNumbers = append(lineNumbers, i+1)
 		}
 	}
 
 	if len(yamlLines) == 0 {
-		return &QueryMetadata{}, nil
+		return &QueryMetadata{}, nil, nil
 	}
 
 	// Join the YAML lines and parse
 	yamlContent := strings.Join(yamlLines, "\n")
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(yamlContent), &root); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse YAML metadata: %s", mapLines(err.Error(), lineNumbers))
+	}
 	var metadata QueryMetadata
-	if err := yaml.Unmarshal([]byte(yamlContent), &metadata); err != nil {
-		return nil, fmt.Errorf("failed to parse YAML metadata (content: %q): %w", yamlContent, err)
+	if err := root.Decode(&metadata); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse YAML metadata: %s", mapLines(err.Error(), lineNumbers))
 	}
 
-	return &metadata, nil
-}
+	version := metadata.CuratedAxiomMCP.Version
+	if version < 0 || version > MetadataVersion {
+		return nil, nil, fmt.Errorf("unsupported metadata Version %d, the latest version is %d", version, MetadataVersion)
+	}
 
-// convertToTemplate converts APL with ///param= annotations to Go template format
-func convertToTemplate(apl string) (string, error) {
-	// Regular expression to match parameter declarations with ///param= annotations
-	// Matches: param_name:type = value [,] ///param=template_replacement
-	// Handle both comma and no-comma cases
-	paramRegex := regexp.MustCompile(`(\w+):(\w+)\s*=\s*([^,\n]+?)(?:,)?\s*///param=([^,\n]+)`)
+	var problems []string
+	for _, problem := range unknownFields(&root, reflect.TypeOf(metadata), "the metadata") {
+		problems = append(problems, fmt.Sprintf("line %d: %s", lineNumbers[problem.line-1], problem.message))
+	}
+	if len(problems) > 0 && version > 0 {
+		return nil, nil, fmt.Errorf("invalid metadata:\n%s", strings.Join(problems, "\n"))
+	}
 
-	// Find all parameter replacements
-	templateAPL := apl
-	matches := paramRegex.FindAllStringSubmatch(apl, -1)
+	// Metadata without a Version ignores unknown fields, as before versions existed
+	return &metadata, problems, nil
+}
 
-	for _, match := range matches {
-		if len(match) < 5 {
+// commentContent returns a line without its comment prefix
+func commentContent(line string) (string, bool) {
+	if strings.HasPrefix(line, "// ") {
+		return strings.TrimPrefix(line, "// "), true
+	} else if strings.HasPrefix(line, "//") {
+		return strings.TrimPrefix(line, "//"), true
+	}
+	return "", false
+}
+
+// continuesYAML reports whether the next non-blank line continues the
+// metadata, that is, it is an indented comment line
+func continuesYAML(lines []string) bool {
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
 			continue
 		}
-
-		paramName := match[1]
-		paramType := match[2]
-		templateReplacement := strings.TrimSpace(match[4])
-
-		// Replace the entire parameter declaration with the template version
-		originalDeclaration := match[0]
-		newDeclaration := paramName + ":" + paramType + " = " + templateReplacement
-		templateAPL = strings.ReplaceAll(templateAPL, originalDeclaration, newDeclaration)
+		content, ok := commentContent(line)
+		return ok && strings.TrimSpace(content) != "" && strings.HasPrefix(content, " ")
 	}
-
-	return templateAPL, nil
-}
\ No newline at end of file
+	return false
+}
@@ -62,8 +62,16 @@ func TestParseStarredQuery(t *testing.T) {
 	if !strings.Contains(parsed.TemplateAPL, "{{.EndTime}}") {
 		t.Errorf("Template should contain {{.EndTime}}")
 	}
-	if !strings.Contains(parsed.TemplateAPL, "{{.EntityId}}") {
-		t.Errorf("Template should contain {{.EntityId}}")
+	if !strings.Contains(parsed.TemplateAPL, "q_entity_id:string = '{{.EntityId}}'") {
+		t.Errorf("Template should declare q_entity_id as '{{.EntityId}}', got:\n%s", parsed.TemplateAPL)
+	}
+	if strings.Contains(parsed.TemplateAPL, "///") {
+		t.Errorf("Template should not contain annotations, got:\n%s", parsed.TemplateAPL)
+	}
+
+	// Test declared parameters
+	if len(parsed.Declared) != 3 || parsed.Declared[2].Name != "q_entity_id" || parsed.Declared[2].Type != "string" {
+		t.Errorf("Unexpected declared parameters: %+v", parsed.Declared)
 	}
 }
 
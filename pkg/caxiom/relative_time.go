@@ -0,0 +1,56 @@
+package caxiom
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// relativeTimeRegex matches a relative time such as now-24h, today or
+// start of week + 1d
+var relativeTimeRegex = regexp.MustCompile(`(?i)^(now|today|yesterday|start of week)\s*(?:([+-])\s*(\S+))?$`)
+
+// ParseDateTime parses a date-time argument. Besides RFC 3339 with any offset
+// it accepts times relative to now: now, today, yesterday and start of week,
+// optionally followed by an offset such as -24h or +7d. Days and weeks start
+// at midnight UTC, weeks on Monday. Relative times are truncated to the
+// minute, so repeated calls render the same APL and can share cached results.
+func ParseDateTime(text string, now time.Time) (time.Time, error) {
+	text = strings.TrimSpace(text)
+	if t, err := time.Parse(time.RFC3339Nano, text); err == nil {
+		return t.UTC(), nil
+	}
+
+	match := relativeTimeRegex.FindStringSubmatch(strings.Join(strings.Fields(text), " "))
+	if match == nil {
+		return time.Time{}, fmt.Errorf("not an RFC 3339 date-time or relative time")
+	}
+
+	now = now.UTC().Truncate(time.Minute)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	var t time.Time
+	switch strings.ToLower(match[1]) {
+	case "now":
+		t = now
+	case "today":
+		t = midnight
+	case "yesterday":
+		t = midnight.AddDate(0, 0, -1)
+	case "start of week":
+		daysSinceMonday := (int(midnight.Weekday()) + 6) % 7
+		t = midnight.AddDate(0, 0, -daysSinceMonday)
+	}
+
+	if match[2] != "" {
+		offset, ok := parseDuration(match[3])
+		if !ok {
+			return time.Time{}, fmt.Errorf("invalid offset %q", match[3])
+		}
+		if match[2] == "-" {
+			offset = -offset
+		}
+		t = t.Add(offset)
+	}
+	return t, nil
+}
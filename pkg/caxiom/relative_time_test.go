@@ -0,0 +1,44 @@
+package caxiom
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDateTime(t *testing.T) {
+	// A Thursday
+	now := time.Date(2025, 6, 26, 12, 30, 15, 500, time.FixedZone("CEST", 2*60*60))
+
+	tests := map[string]string{
+		"2025-06-25T02:00:00+02:00": "2025-06-25T00:00:00Z",
+		"now":                       "2025-06-26T10:30:00Z",
+		"now-24h":                   "2025-06-25T10:30:00Z",
+		"NOW - 30m":                 "2025-06-26T10:00:00Z",
+		"now+PT1H":                  "2025-06-26T11:30:00Z",
+		"today":                     "2025-06-26T00:00:00Z",
+		"yesterday":                 "2025-06-25T00:00:00Z",
+		"yesterday+12h":             "2025-06-25T12:00:00Z",
+		"start of week":             "2025-06-23T00:00:00Z",
+		"Start  of  week -7d":       "2025-06-16T00:00:00Z",
+	}
+	for text, want := range tests {
+		got, err := ParseDateTime(text, now)
+		if err != nil || got.Format(time.RFC3339Nano) != want {
+			t.Errorf("ParseDateTime(%q) = %s, %v, want %s", text, got.Format(time.RFC3339Nano), err, want)
+		}
+	}
+
+	for _, text := range []string{"2025-06-25", "tomorrow", "now-", "now-1y", "yesterday at noon"} {
+		if _, err := ParseDateTime(text, now); err == nil {
+			t.Errorf("Expected error for %q", text)
+		}
+	}
+}
+
+func TestParseDateTimeStartOfWeekOnSunday(t *testing.T) {
+	now := time.Date(2025, 6, 29, 23, 0, 0, 0, time.UTC)
+	got, err := ParseDateTime("start of week", now)
+	if err != nil || !got.Equal(time.Date(2025, 6, 23, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected Monday 2025-06-23, got %s, %v", got, err)
+	}
+}
@@ -0,0 +1,254 @@
+package caxiom
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// suppliedRegex matches the condition of an optional section, such as
+// {{if supplied "Region"}}
+var suppliedRegex = regexp.MustCompile(`\bsupplied\s+"(\w+)"`)
+
+// suppliedNames returns the parameters that optional sections of a template
+// depend on
+func suppliedNames(templateAPL string) map[string]bool {
+	names := make(map[string]bool)
+	for _, match := range suppliedRegex.FindAllStringSubmatch(templateAPL, -1) {
+		names[match[1]] = true
+	}
+	return names
+}
+
+// validateSections checks that a template with optional sections renders
+// valid APL structure with every optional parameter supplied, with each one
+// left out, and with all of them left out. Sections are written as
+// {{if supplied "Region"}}| where region == '{{.Region}}'{{end}}.
+func validateSections(templateAPL string, params []ParameterDefinition) error {
+	names := suppliedNames(templateAPL)
+	if len(names) == 0 {
+		return nil
+	}
+
+	byName := make(map[string]ParameterDefinition, len(params))
+	for _, param := range params {
+		byName[param.Name] = param
+	}
+	var optional []string
+	for _, param := range params {
+		if names[param.Name] {
+			if param.IsRequired() {
+				return fmt.Errorf("optional section for %s: the parameter must set Required: false", param.Name)
+			}
+			optional = append(optional, param.Name)
+		}
+	}
+	for name := range names {
+		if _, ok := byName[name]; !ok {
+			return fmt.Errorf("optional section for %s: unknown parameter", name)
+		}
+	}
+
+	type variant struct {
+		name    string
+		missing map[string]bool
+	}
+	variants := []variant{{"with all optional parameters", map[string]bool{}}}
+	for _, name := range optional {
+		variants = append(variants, variant{"without " + name, map[string]bool{name: true}})
+	}
+	if len(optional) > 1 {
+		all := make(map[string]bool)
+		for _, name := range optional {
+			all[name] = true
+		}
+		variants = append(variants, variant{"without any optional parameters", all})
+	}
+
+	for _, variant := range variants {
+		values := make(map[string]interface{})
+		types := make(map[string]ParamType)
+		supplied := make(map[string]bool)
+		for _, param := range params {
+			paramType, _ := ParseParamType(param.Type)
+			types[param.Name] = paramType
+			if !variant.missing[param.Name] {
+				supplied[param.Name] = true
+				values[param.Name] = sampleValue(param, paramType)
+			} else if value, ok := defaultValue(param, paramType); ok {
+				values[param.Name] = value
+			}
+		}
+
+		executor := &TemplateExecutor{Supplied: supplied}
+		rendered, err := executor.RenderQuery(templateAPL, values, types)
+		if err == nil {
+			err = checkStructure(rendered)
+		}
+		if err != nil {
+			return fmt.Errorf("optional sections %s: %w", variant.name, err)
+		}
+	}
+	return nil
+}
+
+// validateDefaults checks that a template renders when an optional parameter
+// with a Default is left out, such as an optional list whose empty default
+// can't be rendered by inList outside of an optional section
+func validateDefaults(templateAPL string, params []ParameterDefinition) error {
+	types := make(map[string]ParamType, len(params))
+	for _, param := range params {
+		types[param.Name], _ = ParseParamType(param.Type)
+	}
+	render := func(missing string) error {
+		values := make(map[string]interface{})
+		supplied := make(map[string]bool)
+		for _, param := range params {
+			if param.Name != missing {
+				supplied[param.Name] = true
+				values[param.Name] = sampleValue(param, types[param.Name])
+			} else if value, ok := defaultValue(param, types[param.Name]); ok {
+				values[param.Name] = value
+			}
+		}
+		executor := &TemplateExecutor{Supplied: supplied}
+		_, err := executor.RenderQuery(templateAPL, values, types)
+		return err
+	}
+
+	// Templates that fail with every parameter, such as templates with
+	// unknown placeholders, fail for other reasons
+	if render("") != nil {
+		return nil
+	}
+	for _, missing := range params {
+		if missing.IsRequired() || missing.Default == "" {
+			continue
+		}
+		if err := render(missing.Name); err != nil {
+			return fmt.Errorf("parameter %s: the query fails when it is left out and takes its Default %s, use it in an optional section: %w", missing.Name, missing.Default, err)
+		}
+	}
+	return nil
+}
+
+// defaultValue returns the coerced Default of a parameter, as used by a tool
+// call that leaves it out
+func defaultValue(param ParameterDefinition, paramType ParamType) (interface{}, bool) {
+	if param.Default == "" {
+		return nil, false
+	}
+	if paramType.IsList() {
+		items, err := CoerceList(paramType, param.Default, time.Now())
+		return items, err == nil
+	}
+	value, err := CoerceArgument(paramType, param.Default, time.Now())
+	return value, err == nil
+}
+
+// sampleValues are canonical values of every scalar type, for rendering
+// templates at parse time
+var sampleValues = map[ParamType]string{
+	ParamString:   "sample",
+	ParamInteger:  "1",
+	ParamNumber:   "1",
+	ParamBoolean:  "true",
+	ParamDateTime: "2025-01-01T00:00:00Z",
+	ParamDuration: "1h",
+}
+
+// sampleValue returns a coerced value of a parameter for rendering its
+// template at parse time: its example, its default, its first allowed value
+// or a value of its type, whichever is valid first
+func sampleValue(param ParameterDefinition, paramType ParamType) interface{} {
+	candidates := []string{param.Example, param.Default}
+	candidates = append(candidates, param.Enum...)
+	for _, candidate := range candidates {
+		if candidate == "" {
+			continue
+		}
+		if paramType.IsList() {
+			if items, err := CoerceList(paramType, candidate, time.Now()); err == nil && len(items) > 0 {
+				return items
+			}
+			if item, err := CoerceArgument(paramType.Elem(), candidate, time.Now()); err == nil {
+				return []string{item}
+			}
+			continue
+		}
+		if value, err := CoerceArgument(paramType, candidate, time.Now()); err == nil {
+			return value
+		}
+	}
+	if paramType.IsList() {
+		return []string{sampleValues[paramType.Elem()]}
+	}
+	return sampleValues[paramType]
+}
+
+// checkStructure checks rendered APL for the mistakes left behind by optional
+// sections: unbalanced brackets, empty pipe stages, dangling and/or in where
+// clauses and dangling commas
+func checkStructure(apl string) error {
+	tokens, err := tokenize(apl)
+	if err != nil {
+		return err
+	}
+	var significant []token
+	for _, tok := range tokens {
+		if tok.kind != tokenComment {
+			significant = append(significant, tok)
+		}
+	}
+
+	text := func(i int) string {
+		if i < 0 || i >= len(significant) {
+			return ""
+		}
+		return significant[i].text
+	}
+	fail := func(tok token, format string, args ...any) error {
+		return fmt.Errorf("%s: %s", position(apl, tok.start), fmt.Sprintf(format, args...))
+	}
+
+	closing := map[string]string{")": "(", "]": "[", "}": "{"}
+	var open []token
+	for i, tok := range significant {
+		prev, next := text(i-1), text(i+1)
+		switch tok.text {
+		case "(", "[", "{":
+			open = append(open, tok)
+		case ")", "]", "}":
+			if len(open) == 0 || open[len(open)-1].text != closing[tok.text] {
+				return fail(tok, "unbalanced %q", tok.text)
+			}
+			open = open[:len(open)-1]
+		case "|":
+			if prev == "" || prev == "|" || prev == "(" || prev == ";" || next == "" || next == "|" || next == ")" || next == ";" {
+				return fail(tok, "empty pipe stage")
+			}
+		case ",":
+			if prev == "(" || prev == "[" || prev == "," || next == ")" || next == "]" || next == "|" || next == ";" || next == "" {
+				return fail(tok, "dangling comma")
+			}
+		}
+		if tok.kind != tokenIdent {
+			continue
+		}
+		switch tok.text {
+		case "where":
+			if prev == "|" && (next == "" || next == "|" || next == ";" || next == "and" || next == "or") {
+				return fail(tok, "empty where clause")
+			}
+		case "and", "or":
+			if prev == "where" || prev == "(" || prev == "and" || prev == "or" ||
+				next == "" || next == "|" || next == ")" || next == ";" || next == "and" || next == "or" {
+				return fail(tok, "dangling %q", tok.text)
+			}
+		}
+	}
+	if len(open) > 0 {
+		return fail(open[len(open)-1], "unclosed %q", open[len(open)-1].text)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package caxiom
+
+import (
+	"strings"
+	"testing"
+)
+
+const sectionsQuery = `['events']
+| where _time > ago(1d){{if supplied "Region"}}
+| where region == '{{.Region}}'{{end}}
+| where level == 'error'{{if supplied "Service"}} and service {{inList .Service}}{{end}}
+| take {{.Limit}}
+
+// CuratedAxiomMCP:
+//   Params:
+//     - Name: Region
+//       Required: false
+//       Enum: [eu, us]
+//     - Name: Service
+//       Type: string[]
+//       Required: false
+//     - Name: Limit
+//       Type: int
+//       Required: false
+//       Default: "100"`
+
+func TestParseStarredQuerySections(t *testing.T) {
+	parsed, err := ParseStarredQuery("test-query", sectionsQuery)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+	if region := parsed.Metadata.CuratedAxiomMCP.Params[0]; region.IsRequired() || region.Default != "" {
+		t.Errorf("Expected Region to be optional without a default, got %+v", region)
+	}
+
+	tests := []struct {
+		supplied map[string]bool
+		params   map[string]interface{}
+		want     string
+	}{
+		{
+			supplied: map[string]bool{"Region": true},
+			params:   map[string]interface{}{"Region": "eu", "Service": []string{}, "Limit": "100"},
+			want:     "['events']\n| where _time > ago(1d)\n| where region == 'eu'\n| where level == 'error'\n| take 100",
+		},
+		{
+			supplied: map[string]bool{"Service": true, "Limit": true},
+			params:   map[string]interface{}{"Service": []string{"api"}, "Limit": "10"},
+			want:     "['events']\n| where _time > ago(1d)\n| where level == 'error' and service in ('api')\n| take 10",
+		},
+	}
+	types := map[string]ParamType{"Region": ParamString, "Service": "string[]", "Limit": ParamInteger}
+	for _, tt := range tests {
+		executor := &TemplateExecutor{Supplied: tt.supplied}
+		got, err := executor.RenderQuery(parsed.TemplateAPL, tt.params, types)
+		if err != nil {
+			t.Fatalf("Failed to render query: %v", err)
+		}
+		if got, _, _ := strings.Cut(got, "\n\n"); got != tt.want {
+			t.Errorf("Expected:\n%s\ngot:\n%s", tt.want, got)
+		}
+	}
+}
+
+func TestParseStarredQueryInvalidSections(t *testing.T) {
+	metadata := "\n\n// CuratedAxiomMCP:\n//   Params:\n//     - Name: Region\n//       Required: false\n//     - Name: Id"
+	tests := map[string]string{
+		"['events'] | where {{if supplied \"Region\"}}region == '{{.Region}}'{{end}}":                                          "optional sections without Region: line 1, column 14: empty where clause",
+		"['events'] | where id == '{{.Id}}' {{if supplied \"Region\"}}and{{end}} region == '{{.Region}}'":                      "optional sections without Region: failed to execute template",
+		"['events'] | where id == '{{.Id}}'{{if supplied \"Region\"}} and region == '{{.Region}}'{{end}} or":                   "optional sections with all optional parameters: line 1, column 58: dangling \"or\"",
+		"['events'] | where id == '{{.Id}}'{{if supplied \"Id\"}} | take 1{{end}}{{if supplied \"Region\"}} | take 2{{end}}":   "optional section for Id: the parameter must set Required: false",
+		"['events'] | where id == '{{.Id}}'{{if supplied \"Zone\"}} | take 1{{end}}{{if supplied \"Region\"}} | take 2{{end}}": "optional section for Zone: unknown parameter",
+		"['events'] | project {{if supplied \"Region\"}}region, {{end}}":                                                       "optional sections with all optional parameters: line 1, column 28: dangling comma",
+	}
+	for template, wantErr := range tests {
+		if _, err := ParseStarredQuery("test-query", template+metadata); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("Expected error %q for %q, got %v", wantErr, template, err)
+		}
+	}
+}
+
+func TestParseStarredQueryOptionalWithoutSection(t *testing.T) {
+	apl := "['events'] | where region == '{{.Region}}'\n\n// CuratedAxiomMCP:\n//   Params:\n//     - Name: Region\n//       Required: false"
+	if _, err := ParseStarredQuery("test-query", apl); err == nil || !strings.Contains(err.Error(), "optional parameters need a Default") {
+		t.Errorf("Expected missing default error, got %v", err)
+	}
+}
+
+func TestParseStarredQueryOptionalListDefault(t *testing.T) {
+	metadata := "\n\n// CuratedAxiomMCP:\n//   Params:\n//     - Name: Ids\n//       Type: string[]\n//       Required: false"
+
+	_, err := ParseStarredQuery("test-query", "['events'] | where id {{inList .Ids}}"+metadata)
+	if err == nil || !strings.Contains(err.Error(), "parameter Ids: the query fails when it is left out and takes its Default []") {
+		t.Errorf("Expected an error for inList of the empty default, got %v", err)
+	}
+
+	for _, template := range []string{
+		"['events']{{if supplied \"Ids\"}} | where id {{inList .Ids}}{{end}}",
+		"['events']{{if .Ids}} | where id {{inList .Ids}}{{end}}",
+		"['events'] | where id in ({{dynamic .Ids}})",
+	} {
+		parsed, err := ParseStarredQuery("test-query", template+metadata)
+		if err != nil {
+			t.Errorf("Failed to parse %q: %v", template, err)
+			continue
+		}
+		types := map[string]ParamType{"Ids": "string[]"}
+		executor := &TemplateExecutor{Supplied: map[string]bool{}}
+		if _, err := executor.RenderQuery(parsed.TemplateAPL, map[string]interface{}{"Ids": []string{}}, types); err != nil {
+			t.Errorf("Failed to render %q without Ids: %v", template, err)
+		}
+	}
+}
+
+func TestCheckStructure(t *testing.T) {
+	valid := []string{
+		"['events'] | where a == 1 and (b == 2 or c == 3) | project a, b",
+		"declare query_parameters (q:string = 'x');\n['events'] | where msg == '| and ,' // | where",
+	}
+	for _, apl := range valid {
+		if err := checkStructure(apl); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", apl, err)
+		}
+	}
+
+	invalid := map[string]string{
+		"['events'] | | take 1":            "empty pipe stage",
+		"['events'] | take 1 |":            "empty pipe stage",
+		"['events'] | where | take 1":      "empty where clause",
+		"['events'] | where a == 1 and":    `dangling "and"`,
+		"['events'] | where (or a == 1)":   `dangling "or"`,
+		"['events'] | project a, | take 1": "dangling comma",
+		"['events'] | where f(a":           `unclosed "("`,
+		"['events'] | where a == 1)":       `unbalanced ")"`,
+	}
+	for apl, wantErr := range invalid {
+		if err := checkStructure(apl); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("Expected error %q for %q, got %v", wantErr, apl, err)
+		}
+	}
+}
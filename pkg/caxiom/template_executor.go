@@ -3,25 +3,46 @@ package caxiom
 import (
 	"bytes"
 	"fmt"
+	"regexp"
+	"strings"
 	"text/template"
+	"text/template/parse"
+	"time"
+	"unicode/utf8"
 )
 
 // TemplateExecutor handles rendering of APL query templates
-type TemplateExecutor struct{}
+type TemplateExecutor struct {
+	// Supplied names the parameters given in the tool call, for the supplied
+	// helper. When nil, every parameter with a value counts as supplied.
+	Supplied map[string]bool
+}
 
 // NewTemplateExecutor creates a new template executor
 func NewTemplateExecutor() *TemplateExecutor {
 	return &TemplateExecutor{}
 }
 
-// RenderTemplate renders an APL template with the provided parameters
+// RenderTemplate renders an APL template with the provided parameters. The
+// helpers of templateFuncs are available, and placeholders for parameters
+// that are not provided are an error.
 func (te *TemplateExecutor) RenderTemplate(templateAPL string, params map[string]interface{}) (string, error) {
 	// Create a new template
-	tmpl, err := template.New("apl_query").Parse(templateAPL)
+	tmpl, err := ParseTemplate(templateAPL)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse template: %w", err)
 	}
 
+	tmpl.Funcs(template.FuncMap{
+		"supplied": func(name string) bool {
+			if te.Supplied != nil {
+				return te.Supplied[name]
+			}
+			_, ok := params[name]
+			return ok
+		},
+	})
+
 	// Execute the template with the provided parameters
 	var buf bytes.Buffer
 	if err := tmpl.Execute(&buf, params); err != nil {
@@ -29,4 +50,340 @@ func (te *TemplateExecutor) RenderTemplate(templateAPL string, params map[string
 	}
 
 	return buf.String(), nil
-}
\ No newline at end of file
+}
+
+// ParseTemplate parses an APL template with the template helpers
+func ParseTemplate(templateAPL string) (*template.Template, error) {
+	return template.New("apl_query").Funcs(templateFuncs).Option("missingkey=error").Parse(templateAPL)
+}
+
+// Placeholders returns the parameters a template refers to, such as EntityId
+// for {{.EntityId}} or {{quote .EntityId}}, in order of first appearance
+func Placeholders(templateAPL string) ([]string, error) {
+	tmpl, err := ParseTemplate(templateAPL)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.ChainNode:
+			walk(n.Node)
+		case *parse.FieldNode:
+			add(n.Ident[0])
+		case *parse.VariableNode:
+			if len(n.Ident) > 1 && n.Ident[0] == "$" {
+				add(n.Ident[1])
+			}
+		}
+	}
+	walk(tmpl.Tree.Root)
+	return names, nil
+}
+
+// RenderQuery renders a curated query template with the parameter values.
+// Unlike RenderTemplate it knows the type of every parameter, so values can't
+// change the structure of the query:
+//
+//   - string values are escaped, and must be placed inside an APL string
+//     literal in the template, or rendered with a helper such as quote
+//   - other values must be canonical literals of their type, as returned by
+//     CoerceArgument
+//   - lists are rendered with a helper such as inList or dynamic
+//
+// After rendering, the query is tokenized and compared with a rendering in
+// which every string value prints as a harmless marker. Any difference means
+// a value broke out of its literal, and the query is rejected.
+func (te *TemplateExecutor) RenderQuery(templateAPL string, params map[string]interface{}, types map[string]ParamType) (string, error) {
+	escaped := make(map[string]interface{}, len(params))
+	probe := make(map[string]interface{}, len(params))
+	markers := make(map[string]string)
+	for name, value := range params {
+		paramType, ok := types[name]
+		if !ok {
+			return "", fmt.Errorf("parameter %s: unknown type", name)
+		}
+		if paramType.IsList() {
+			items, ok := value.([]string)
+			if !ok {
+				return "", fmt.Errorf("parameter %s: expected a list of strings, got %T", name, value)
+			}
+			escapedItems, probeItems, err := listValues(paramType.Elem(), items)
+			if err != nil {
+				return "", fmt.Errorf("parameter %s: %w", name, err)
+			}
+			escaped[name] = escapedItems
+			probe[name] = probeItems
+			for _, item := range probeItems {
+				if marker, ok := item.(probeString); ok {
+					markers[marker.String()] = name
+				}
+			}
+			continue
+		}
+
+		text, ok := value.(string)
+		if !ok {
+			return "", fmt.Errorf("parameter %s: expected a string value, got %T", name, value)
+		}
+
+		if paramType != ParamString {
+			if err := checkLiteral(paramType, text); err != nil {
+				return "", fmt.Errorf("parameter %s: %w", name, err)
+			}
+			if paramType == ParamBoolean {
+				// Booleans work with if and iif
+				escaped[name] = text == "true"
+				probe[name] = text == "true"
+				continue
+			}
+			escaped[name] = literal(text)
+			probe[name] = literal(text)
+			continue
+		}
+
+		quoted, err := EscapeString(text)
+		if err != nil {
+			return "", fmt.Errorf("parameter %s: %w", name, err)
+		}
+		escaped[name] = escapedString(quoted)
+		probe[name] = probeString(quoted)
+		markers[probeString(quoted).String()] = name
+	}
+
+	rendered, err := te.RenderTemplate(templateAPL, escaped)
+	if err != nil {
+		return "", err
+	}
+	probed, err := te.RenderTemplate(templateAPL, probe)
+	if err != nil {
+		return "", err
+	}
+
+	probeTokens, err := tokenize(probed)
+	if err != nil {
+		return "", fmt.Errorf("failed to tokenize query template: %w", err)
+	}
+	for _, tok := range probeTokens {
+		if tok.kind == tokenString || tok.kind == tokenComment {
+			continue
+		}
+		for marker, name := range markers {
+			if strings.Contains(tok.text, marker) {
+				return "", fmt.Errorf("parameter %s: string parameters must be placed inside a string literal such as '{{.%s}}'", name, name)
+			}
+		}
+	}
+
+	renderedTokens, err := tokenize(rendered)
+	if err != nil || !sameStructure(probeTokens, renderedTokens) {
+		return "", fmt.Errorf("a parameter value breaks out of its literal and would change the query, check the values for quotes and backslashes")
+	}
+	return rendered, nil
+}
+
+// listValues returns the items of a list parameter as they are rendered, and
+// as they are probed
+func listValues(elemType ParamType, items []string) ([]any, []any, error) {
+	escaped := make([]any, len(items))
+	probe := make([]any, len(items))
+	for i, item := range items {
+		if elemType != ParamString {
+			if err := checkLiteral(elemType, item); err != nil {
+				return nil, nil, fmt.Errorf("item %d: %w", i+1, err)
+			}
+			escaped[i] = literal(item)
+			probe[i] = literal(item)
+			continue
+		}
+		quoted, err := EscapeString(item)
+		if err != nil {
+			return nil, nil, fmt.Errorf("item %d: %w", i+1, err)
+		}
+		escaped[i] = escapedString(quoted)
+		probe[i] = probeString(quoted)
+	}
+	return escaped, probe, nil
+}
+
+// EscapeString escapes a value for use inside a single or double quoted APL
+// string literal. Invalid UTF-8 and control characters other than tab,
+// newline and carriage return are rejected.
+func EscapeString(value string) (string, error) {
+	if !utf8.ValidString(value) {
+		return "", fmt.Errorf("value is not valid UTF-8")
+	}
+	var builder strings.Builder
+	for _, r := range value {
+		switch r {
+		case '\\':
+			builder.WriteString(`\\`)
+		case '\'':
+			builder.WriteString(`\'`)
+		case '"':
+			builder.WriteString(`\"`)
+		case '\n':
+			builder.WriteString(`\n`)
+		case '\r':
+			builder.WriteString(`\r`)
+		case '\t':
+			builder.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				return "", fmt.Errorf("value contains control character %U", r)
+			}
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String(), nil
+}
+
+// literalRegexes match the canonical literals of non-string parameter types
+var literalRegexes = map[ParamType]*regexp.Regexp{
+	ParamInteger:  regexp.MustCompile(`^-?\d+$`),
+	ParamNumber:   regexp.MustCompile(`^-?\d+(\.\d+)?$`),
+	ParamBoolean:  regexp.MustCompile(`^(true|false)$`),
+	ParamDuration: regexp.MustCompile(`^\d+(d|h|m|s|ms|microsecond)$`),
+}
+
+// checkLiteral checks that a value is a canonical literal of its type
+func checkLiteral(paramType ParamType, value string) error {
+	if paramType == ParamDateTime {
+		if _, err := time.Parse(time.RFC3339Nano, value); err != nil {
+			return fmt.Errorf("invalid %s value %q", paramType, value)
+		}
+		return nil
+	}
+	re, ok := literalRegexes[paramType]
+	if !ok {
+		return fmt.Errorf("unsupported type %q", paramType)
+	}
+	if !re.MatchString(value) {
+		return fmt.Errorf("invalid %s value %q", paramType, value)
+	}
+	return nil
+}
+
+// tokenize splits APL into tokens
+func tokenize(apl string) ([]token, error) {
+	lex := &lexer{src: apl}
+	var tokens []token
+	for {
+		tok, err := lex.next()
+		if err != nil {
+			return nil, err
+		}
+		if tok.kind == tokenEOF {
+			return tokens, nil
+		}
+		tokens = append(tokens, tok)
+	}
+}
+
+// StripComments replaces the comments of APL with spaces, keeping the
+// position of everything else
+func StripComments(apl string) (string, error) {
+	tokens, err := tokenize(apl)
+	if err != nil {
+		return "", err
+	}
+	stripped := []byte(apl)
+	for _, tok := range tokens {
+		if tok.kind != tokenComment {
+			continue
+		}
+		for i := tok.start; i < tok.end; i++ {
+			stripped[i] = ' '
+		}
+	}
+	return string(stripped), nil
+}
+
+// QueryDataset returns the dataset the tabular expression of an APL query
+// starts from, written as ['name'] or as a plain name. It returns "" if the
+// query doesn't start from a dataset, e.g. a union, or doesn't tokenize.
+func QueryDataset(apl string) string {
+	tokens, err := tokenize(apl)
+	if err != nil {
+		return ""
+	}
+	// The tabular expression is the last statement
+	var statement []token
+	for _, tok := range tokens {
+		switch {
+		case tok.kind == tokenComment:
+		case tok.kind == tokenOther && tok.text == ";":
+			statement = statement[:0]
+		default:
+			statement = append(statement, tok)
+		}
+	}
+
+	switch {
+	case len(statement) >= 3 && statement[0].text == "[" && statement[1].kind == tokenString && statement[2].text == "]":
+		return statement[1].text[1 : len(statement[1].text)-1]
+	case len(statement) >= 1 && statement[0].kind == tokenIdent && (len(statement) == 1 || statement[1].text == "|"):
+		return statement[0].text
+	}
+	return ""
+}
+
+// sameStructure reports whether two token lists only differ in the content of
+// string literals and comments
+func sameStructure(a, b []token) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].kind != b[i].kind {
+			return false
+		}
+		if a[i].kind != tokenString && a[i].kind != tokenComment && a[i].text != b[i].text {
+			return false
+		}
+	}
+	return true
+}
@@ -57,4 +57,257 @@ func TestRenderTemplateError(t *testing.T) {
 	if err == nil {
 		t.Error("Expected error for invalid template syntax")
 	}
-}
\ No newline at end of file
+}
+
+const entityTemplate = `declare query_parameters (
+    q_start:datetime = datetime({{.StartTime}}),
+    q_limit:long = {{.Limit}},
+    q_entity_id:string = '{{.EntityId}}'
+);
+['events'] | where _time > q_start and id == q_entity_id | limit q_limit`
+
+var entityTypes = map[string]ParamType{
+	"StartTime": ParamDateTime,
+	"Limit":     ParamInteger,
+	"EntityId":  ParamString,
+}
+
+func TestRenderQueryEscapesStrings(t *testing.T) {
+	executor := NewTemplateExecutor()
+
+	result, err := executor.RenderQuery(entityTemplate, map[string]interface{}{
+		"StartTime": "2025-06-25T00:00:00Z",
+		"Limit":     "10",
+		"EntityId":  `x' or 1==1 or id=='`,
+	}, entityTypes)
+	if err != nil {
+		t.Fatalf("Failed to render query: %v", err)
+	}
+	if !strings.Contains(result, `q_entity_id:string = 'x\' or 1==1 or id==\''`) {
+		t.Errorf("Expected escaped entity id, got:\n%s", result)
+	}
+}
+
+func TestRenderQueryRejectsInvalidLiterals(t *testing.T) {
+	executor := NewTemplateExecutor()
+
+	tests := map[string]map[string]interface{}{
+		"integer":    {"StartTime": "2025-06-25T00:00:00Z", "Limit": "10 | take 1", "EntityId": "a"},
+		"datetime":   {"StartTime": "2025-06-25T00:00:00Z) or (true", "Limit": "10", "EntityId": "a"},
+		"control":    {"StartTime": "2025-06-25T00:00:00Z", "Limit": "10", "EntityId": "a\x00b"},
+		"non-string": {"StartTime": "2025-06-25T00:00:00Z", "Limit": 10, "EntityId": "a"},
+	}
+	for name, params := range tests {
+		if _, err := executor.RenderQuery(entityTemplate, params, entityTypes); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestRenderQueryRequiresQuotedStrings(t *testing.T) {
+	executor := NewTemplateExecutor()
+
+	_, err := executor.RenderQuery(`['events'] | where id == {{.EntityId}}`,
+		map[string]interface{}{"EntityId": "abc"}, map[string]ParamType{"EntityId": ParamString})
+	if err == nil || !strings.Contains(err.Error(), "must be placed inside a string literal") {
+		t.Errorf("Expected unquoted string parameter to be rejected, got %v", err)
+	}
+}
+
+func TestRenderQueryRejectsVerbatimBreakout(t *testing.T) {
+	executor := NewTemplateExecutor()
+
+	// Backslashes don't escape quotes in verbatim strings
+	_, err := executor.RenderQuery(`['events'] | where id == @'{{.EntityId}}'`,
+		map[string]interface{}{"EntityId": "x' or true or id == '"}, map[string]ParamType{"EntityId": ParamString})
+	if err == nil || !strings.Contains(err.Error(), "breaks out of its literal") {
+		t.Errorf("Expected breakout to be rejected, got %v", err)
+	}
+}
+
+// FuzzRenderQueryString checks that no string value can change the structure
+// of a query: rendering either fails, or the value ends up as the content of a
+// single string literal.
+func FuzzRenderQueryString(f *testing.F) {
+	for _, seed := range []string{
+		"example-entity-id",
+		`x' or 1==1 or id=='`,
+		`x" or 1==1 or id=="`,
+		`\' or true //`,
+		"a\\",
+		"line\nbreak // comment",
+		"{{.Limit}}",
+		"') | union ['secrets'] | where ('",
+		"ünïcødé 🎉",
+		"\x80",
+	} {
+		f.Add(seed)
+	}
+
+	executor := NewTemplateExecutor()
+	f.Fuzz(func(t *testing.T, value string) {
+		for _, template := range []string{entityTemplate, strings.ReplaceAll(entityTemplate, "'{{.EntityId}}'", `"{{.EntityId}}"`)} {
+			result, err := executor.RenderQuery(template, map[string]interface{}{
+				"StartTime": "2025-06-25T00:00:00Z",
+				"Limit":     "10",
+				"EntityId":  value,
+			}, entityTypes)
+			if err != nil {
+				continue
+			}
+
+			block, err := ParseDeclareBlock(result)
+			if err != nil {
+				t.Fatalf("Rendered query doesn't parse: %v\n%s", err, result)
+			}
+			param, ok := block.Param("q_entity_id")
+			if !ok {
+				t.Fatalf("Rendered query lost q_entity_id:\n%s", result)
+			}
+			tokens, err := tokenize(param.Default)
+			if err != nil || len(tokens) != 1 || tokens[0].kind != tokenString {
+				t.Fatalf("Expected a single string literal, got %q", param.Default)
+			}
+			if got, ok := unescapeString(param.Default[1 : len(param.Default)-1]); !ok || got != value {
+				t.Fatalf("Expected literal to hold %q, got %q", value, got)
+			}
+			if !strings.HasSuffix(result, "['events'] | where _time > q_start and id == q_entity_id | limit q_limit") {
+				t.Fatalf("Query body changed:\n%s", result)
+			}
+		}
+	})
+}
+
+func TestRenderTemplateMissingKey(t *testing.T) {
+	executor := NewTemplateExecutor()
+
+	_, err := executor.RenderTemplate(`['events'] | where id == '{{.EntityID}}'`, map[string]interface{}{"EntityId": "x"})
+	if err == nil || !strings.Contains(err.Error(), `map has no entry for key "EntityID"`) {
+		t.Errorf("Expected missing key error, got %v", err)
+	}
+}
+
+func TestRenderQueryHelpers(t *testing.T) {
+	executor := NewTemplateExecutor()
+
+	templateAPL := `['events']
+| where _time > {{datetime .Start}} and _time < {{datetime "2025-06-26T02:00:00+02:00"}}
+| where level {{inList "error" .Level}} and code {{inList .Limit 500}}
+| where id == {{quote .EntityId}} and msg has '{{escape .EntityId}}'
+| summarize count() by bin(_time, {{timespan .Window}}){{if eq .Level "warn"}}
+| where {{iif .Verbose "true" "false"}}{{end}}
+| take {{default 10 .Limit}}{{if .Quiet}} | where false{{end}}`
+	types := map[string]ParamType{
+		"Start":    ParamDateTime,
+		"Level":    ParamString,
+		"Limit":    ParamInteger,
+		"EntityId": ParamString,
+		"Window":   ParamDuration,
+		"Verbose":  ParamBoolean,
+		"Quiet":    ParamBoolean,
+	}
+	params := map[string]interface{}{
+		"Start":    "2025-06-25T00:00:00Z",
+		"Level":    "warn",
+		"Limit":    "100",
+		"EntityId": "it's",
+		"Window":   "90m",
+		"Verbose":  "true",
+		"Quiet":    "false",
+	}
+
+	got, err := executor.RenderQuery(templateAPL, params, types)
+	if err != nil {
+		t.Fatalf("Failed to render query: %v", err)
+	}
+	want := `['events']
+| where _time > datetime(2025-06-25T00:00:00Z) and _time < datetime(2025-06-26T00:00:00Z)
+| where level in ('error', 'warn') and code in (100, 500)
+| where id == 'it\'s' and msg has 'it\'s'
+| summarize count() by bin(_time, 90m)
+| where true
+| take 100`
+	if got != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestRenderQueryHelpersRejectBreakout(t *testing.T) {
+	executor := NewTemplateExecutor()
+	types := map[string]ParamType{"Id": ParamString}
+
+	if _, err := executor.RenderQuery(`['events'] | where id == {{default "x" .Id}}`, map[string]interface{}{"Id": "a"}, types); err == nil {
+		t.Error("Expected error for string parameter outside a string literal")
+	}
+	if _, err := executor.RenderQuery(`['events'] | where id {{inList .Id}}`, map[string]interface{}{"Id": "a' or true or 'b"}, types); err != nil {
+		t.Errorf("Expected quoted value in inList, got %v", err)
+	}
+	if _, err := executor.RenderQuery(`['events'] | where _time > {{datetime .Id}}`, map[string]interface{}{"Id": "now) | take 1 //"}, types); err == nil {
+		t.Error("Expected error for invalid datetime")
+	}
+}
+
+func TestRenderQueryLists(t *testing.T) {
+	executor := NewTemplateExecutor()
+	types := map[string]ParamType{"Ids": "string[]", "Codes": "integer[]"}
+	params := map[string]interface{}{
+		"Ids":   []string{"a", "b' or true or '"},
+		"Codes": []string{"500", "503"},
+	}
+
+	got, err := executor.RenderQuery(`declare query_parameters (q_ids:dynamic = {{dynamic .Ids}});
+['events'] | where id {{inList .Ids}} and code {{inList .Codes}}`, params, types)
+	if err != nil {
+		t.Fatalf("Failed to render query: %v", err)
+	}
+	want := `declare query_parameters (q_ids:dynamic = dynamic(['a', 'b\' or true or \'']));
+['events'] | where id in ('a', 'b\' or true or \'') and code in (500, 503)`
+	if got != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, got)
+	}
+
+	if _, err := executor.RenderQuery(`['events'] | where id in ({{.Ids}})`, params, types); err == nil {
+		t.Error("Expected error for a list rendered without a helper")
+	}
+	if _, err := executor.RenderQuery(`['events'] | where code in ({{.Codes}})`, map[string]interface{}{"Codes": []string{"1) or (true"}}, types); err == nil {
+		t.Error("Expected error for an invalid integer item")
+	}
+}
+
+func TestPlaceholders(t *testing.T) {
+	template := `['events'] | where id {{inList .Ids}}{{if supplied "Region"}} and region == '{{.Region}}'{{end}}{{with .Level}} and level == '{{$.Level}}'{{end}} | take {{default 10 .Limit}} | where id == '{{.Ids}}'`
+	names, err := Placeholders(template)
+	if err != nil {
+		t.Fatalf("Failed to find placeholders: %v", err)
+	}
+	if got := strings.Join(names, ","); got != "Ids,Region,Level,Limit" {
+		t.Errorf("Expected Ids,Region,Level,Limit, got %s", got)
+	}
+}
+
+func TestStripComments(t *testing.T) {
+	apl := "['events'] // take 1\n| where msg == '// not a comment'"
+	got, err := StripComments(apl)
+	if err != nil {
+		t.Fatalf("Failed to strip comments: %v", err)
+	}
+	if want := "['events']" + strings.Repeat(" ", 10) + "\n| where msg == '// not a comment'"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestQueryDataset(t *testing.T) {
+	tests := map[string]string{
+		"['logs-prod'] | take 1":       "logs-prod",
+		`["logs.prod"]`:                "logs.prod",
+		"events\n| where level == 'x'": "events",
+		"// ['old']\ndeclare query_parameters(q_x:string = 'a;b');\n['events'] | take 1": "events",
+		"union ['a'], ['b']": "",
+		"print x = 1":        "",
+	}
+	for apl, want := range tests {
+		if got := QueryDataset(apl); got != want {
+			t.Errorf("QueryDataset(%q) = %q, want %q", apl, got, want)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package caxiom
+
+import (
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// escapedString is a string parameter value that RenderQuery has already
+// escaped for use inside an APL string literal
+type escapedString string
+
+// probeString is a string parameter value of the probe rendering of
+// RenderQuery. It compares like the escaped value, so templates take the same
+// branches, but prints as a marker that is traced through the query.
+type probeString string
+
+// String returns the marker of the value
+func (p probeString) String() string {
+	hash := fnv.New64a()
+	hash.Write([]byte(p))
+	return "caxiomprobe" + strconv.FormatUint(hash.Sum64(), 36) + "x"
+}
+
+// literal is a parameter value that RenderQuery has checked to be a canonical
+// APL literal of a non-string type, such as 42, true or 7d
+type literal string
+
+// templateFuncs are the helpers available in curated query templates:
+//
+//   - quote: a value as a single quoted APL string literal, e.g. 'abc'
+//   - escape: a value escaped for use inside an APL string literal
+//   - inList: values as an in clause, e.g. in ('a', 'b') or in (1, 2)
+//   - dynamic: values as a dynamic array, e.g. dynamic(['a', 'b'])
+//   - datetime: a date-time as an APL datetime, e.g. datetime(2025-06-25T00:00:00Z)
+//   - timespan: a duration as an APL timespan, e.g. 90m for 1.5h or PT90M
+//   - default: the second argument, or the first if the second is empty
+//   - empty: whether a value is empty, for use with if
+//   - iif: the second argument if the first is not empty, otherwise the third
+//   - supplied: whether an optional parameter was given in the tool call, for
+//     optional sections such as {{if supplied "Region"}}...{{end}}
+var templateFuncs = template.FuncMap{
+	"quote": func(value any) (string, error) {
+		text, err := stringLiteralText(value)
+		if err != nil {
+			return "", err
+		}
+		return "'" + text + "'", nil
+	},
+	"escape":   stringLiteralText,
+	"inList":   inList,
+	"dynamic":  dynamicArray,
+	"datetime": datetimeLiteral,
+	"timespan": timespanLiteral,
+	"default": func(fallback, value any) any {
+		if isEmpty(value) {
+			return fallback
+		}
+		return value
+	},
+	"empty": isEmpty,
+	"supplied": func(name string) bool {
+		// Replaced by RenderTemplate
+		return true
+	},
+	"iif": func(condition, then, otherwise any) any {
+		if !isEmpty(condition) {
+			return then
+		}
+		return otherwise
+	},
+}
+
+// stringLiteralText returns a value escaped for use inside an APL string
+// literal. Values escaped by RenderQuery are not escaped again.
+func stringLiteralText(value any) (string, error) {
+	switch v := value.(type) {
+	case escapedString:
+		return string(v), nil
+	case probeString:
+		return v.String(), nil
+	case literal:
+		return string(v), nil
+	case string:
+		return EscapeString(v)
+	case nil:
+		return "", fmt.Errorf("missing value")
+	default:
+		return EscapeString(fmt.Sprint(v))
+	}
+}
+
+// inList renders values as an APL in clause, see listItems
+func inList(values ...any) (string, error) {
+	items, err := listItems(values)
+	if err != nil {
+		return "", fmt.Errorf("inList: %w", err)
+	}
+	if len(items) == 0 {
+		return "", fmt.Errorf("inList: needs at least one value")
+	}
+	return "in (" + strings.Join(items, ", ") + ")", nil
+}
+
+// dynamicArray renders values as an APL dynamic array, see listItems
+func dynamicArray(values ...any) (string, error) {
+	items, err := listItems(values)
+	if err != nil {
+		return "", fmt.Errorf("dynamic: %w", err)
+	}
+	return "dynamic([" + strings.Join(items, ", ") + "])", nil
+}
+
+// listItems renders values as APL literals. Slices are flattened. Strings
+// are quoted, numbers, booleans and non-string parameters are not.
+func listItems(values []any) ([]string, error) {
+	var items []string
+	var add func(value any) error
+	add = func(value any) error {
+		switch v := value.(type) {
+		case literal:
+			items = append(items, string(v))
+		case int, int64, float64, bool:
+			items = append(items, fmt.Sprint(v))
+		case escapedString, probeString, string:
+			text, err := stringLiteralText(v)
+			if err != nil {
+				return err
+			}
+			items = append(items, "'"+text+"'")
+		default:
+			rv := reflect.ValueOf(value)
+			if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+				return fmt.Errorf("unsupported value of type %T", value)
+			}
+			for i := 0; i < rv.Len(); i++ {
+				if err := add(rv.Index(i).Interface()); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	for _, value := range values {
+		if err := add(value); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// datetimeLiteral renders a date-time as an APL datetime literal. Strings may
+// be RFC 3339 or relative to the current time, see ParseDateTime.
+func datetimeLiteral(value any) (string, error) {
+	t, ok := value.(time.Time)
+	if !ok {
+		var err error
+		t, err = ParseDateTime(fmt.Sprint(underlying(value)), time.Now())
+		if err != nil {
+			return "", fmt.Errorf("datetime: %w: %q", err, underlying(value))
+		}
+	}
+	return "datetime(" + t.UTC().Format(time.RFC3339Nano) + ")", nil
+}
+
+// timespanLiteral renders a duration as an APL timespan literal
+func timespanLiteral(value any) (string, error) {
+	d, ok := value.(time.Duration)
+	if !ok {
+		d, ok = parseDuration(strings.TrimSpace(fmt.Sprint(underlying(value))))
+		if !ok {
+			return "", fmt.Errorf("timespan: not a duration: %q", underlying(value))
+		}
+	}
+	return FormatTimespan(d), nil
+}
+
+// isEmpty reports whether a value is nil, false, zero or has no elements
+func isEmpty(value any) bool {
+	if value == nil {
+		return true
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return rv.Len() == 0
+	default:
+		return rv.IsZero()
+	}
+}
+
+// underlying returns the text of parameter values, without the marker of
+// probe values, so helpers that validate values see the same text in both
+// renderings of RenderQuery
+func underlying(value any) any {
+	switch v := value.(type) {
+	case escapedString:
+		return string(v)
+	case probeString:
+		return string(v)
+	case literal:
+		return string(v)
+	}
+	return value
+}
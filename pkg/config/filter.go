@@ -0,0 +1,108 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"slices"
+
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom"
+	"github.com/roessland/curated-axiom-mcp/pkg/caxiom"
+)
+
+// QueryFilter selects which curated starred queries become tools. Starred
+// queries are fetched for all users of the organization and filtered by the
+// client, so without a filter every teammate's curated query is exposed.
+type QueryFilter struct {
+	Owners   []string `yaml:"owners,omitempty" mapstructure:"owners"`       // Owners (Who) of exposed queries, empty allows all
+	Datasets []string `yaml:"datasets,omitempty" mapstructure:"datasets"`   // Glob patterns of the dataset of exposed queries, empty allows all
+	Kinds    []string `yaml:"kinds,omitempty" mapstructure:"kinds"`         // Kinds of exposed queries, empty allows all
+	Include  []string `yaml:"include,omitempty" mapstructure:"include"`     // Glob patterns of the query name or tool name, empty includes all
+	Exclude  []string `yaml:"exclude,omitempty" mapstructure:"exclude"`     // Glob patterns of the query name or tool name, applied after include
+	AllowAll bool     `yaml:"allow_all,omitempty" mapstructure:"allow_all"` // Allow every query, instead of inheriting the filter of the axiom section
+}
+
+// IsZero reports whether the filter is unset, so a profile inherits the
+// filter of the axiom section
+func (f *QueryFilter) IsZero() bool {
+	return !f.AllowAll && len(f.Owners) == 0 && len(f.Datasets) == 0 && len(f.Kinds) == 0 &&
+		len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+// Validate checks that all glob patterns are valid, and that allow_all isn't
+// combined with other fields
+func (f *QueryFilter) Validate() error {
+	if others := (QueryFilter{Owners: f.Owners, Datasets: f.Datasets, Kinds: f.Kinds, Include: f.Include, Exclude: f.Exclude}); f.AllowAll && !others.IsZero() {
+		return fmt.Errorf("allow_all cannot be combined with other filter fields")
+	}
+	for _, patterns := range [][]string{f.Datasets, f.Include, f.Exclude} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Match reports whether a starred query passes the filter, and why. The tool
+// name may be empty if the query doesn't declare one.
+func (f *QueryFilter) Match(name, toolName, owner, dataset, kind string) (bool, string) {
+	if f.IsZero() {
+		return true, "no filter configured"
+	}
+	if f.AllowAll {
+		return true, "allow_all is set"
+	}
+	if len(f.Owners) > 0 && !slices.Contains(f.Owners, owner) {
+		return false, fmt.Sprintf("owner %q is not in owners", owner)
+	}
+	if _, ok := matchAny(f.Datasets, dataset); len(f.Datasets) > 0 && !ok {
+		if dataset == "" {
+			return false, "dataset is unknown, so it does not match datasets"
+		}
+		return false, fmt.Sprintf("dataset %q does not match datasets", dataset)
+	}
+	if len(f.Kinds) > 0 && !slices.Contains(f.Kinds, kind) {
+		return false, fmt.Sprintf("kind %q is not in kinds", kind)
+	}
+
+	reason := "passes owner, dataset and kind filters"
+	if len(f.Include) > 0 {
+		pattern, ok := matchAny(f.Include, name, toolName)
+		if !ok {
+			return false, "name does not match any include pattern"
+		}
+		reason = fmt.Sprintf("name matches include pattern %q", pattern)
+	}
+	if pattern, ok := matchAny(f.Exclude, name, toolName); ok {
+		return false, fmt.Sprintf("name matches exclude pattern %q", pattern)
+	}
+	return true, reason
+}
+
+// MatchStarredQuery reports whether a starred query passes the filter, and
+// why. Starred queries created by the API don't always have a dataset, so the
+// dataset named in the APL is used instead.
+func (f *QueryFilter) MatchStarredQuery(sq axiom.StarredQuery, toolName string) (bool, string) {
+	dataset := sq.Dataset
+	if dataset == "" {
+		dataset = caxiom.QueryDataset(sq.Query.APL)
+	}
+	return f.Match(sq.Name, toolName, sq.Who, dataset, sq.Kind)
+}
+
+// matchAny returns the first pattern matching any of the values. Empty values
+// never match.
+func matchAny(patterns []string, values ...string) (string, bool) {
+	for _, pattern := range patterns {
+		for _, value := range values {
+			if value == "" {
+				continue
+			}
+			if matched, _ := path.Match(pattern, value); matched {
+				return pattern, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom"
+)
+
+func TestQueryFilterMatch(t *testing.T) {
+	filter := QueryFilter{
+		Owners:   []string{"alice", "bob"},
+		Datasets: []string{"logs-*"},
+		Kinds:    []string{"apl"},
+		Include:  []string{"team_*"},
+		Exclude:  []string{"*_draft"},
+	}
+
+	tests := []struct {
+		name     string
+		toolName string
+		owner    string
+		dataset  string
+		kind     string
+		want     bool
+		reason   string
+	}{
+		{"Team errors", "team_errors", "alice", "logs-prod", "apl", true, `name matches include pattern "team_*"`},
+		{"Team errors", "team_errors", "carol", "logs-prod", "apl", false, `owner "carol" is not in owners`},
+		{"Team errors", "team_errors", "bob", "traces", "apl", false, `dataset "traces" does not match datasets`},
+		{"Team errors", "team_errors", "bob", "logs-prod", "stream", false, `kind "stream" is not in kinds`},
+		{"Other errors", "other_errors", "bob", "logs-prod", "apl", false, "name does not match any include pattern"},
+		{"team_errors", "", "bob", "logs-prod", "apl", true, `name matches include pattern "team_*"`},
+		{"Team errors", "team_errors_draft", "bob", "logs-prod", "apl", false, `name matches exclude pattern "*_draft"`},
+	}
+	for _, tt := range tests {
+		got, reason := filter.Match(tt.name, tt.toolName, tt.owner, tt.dataset, tt.kind)
+		if got != tt.want || reason != tt.reason {
+			t.Errorf("Match(%q, %q, %q, %q, %q) = %v, %q, want %v, %q",
+				tt.name, tt.toolName, tt.owner, tt.dataset, tt.kind, got, reason, tt.want, tt.reason)
+		}
+	}
+}
+
+func TestQueryFilterZero(t *testing.T) {
+	var filter QueryFilter
+	if ok, reason := filter.Match("Any", "", "", "", ""); !ok || reason != "no filter configured" {
+		t.Errorf("Expected empty filter to allow everything, got %v, %q", ok, reason)
+	}
+	if err := (&QueryFilter{Include: []string{"["}}).Validate(); err == nil {
+		t.Error("Expected error for invalid pattern")
+	}
+}
+
+func TestQueryFilterAllowAll(t *testing.T) {
+	filter := QueryFilter{AllowAll: true}
+	if filter.IsZero() {
+		t.Error("Expected allow_all to count as a filter, so it isn't inherited")
+	}
+	if ok, reason := filter.Match("Any", "", "carol", "traces", "apl"); !ok || reason != "allow_all is set" {
+		t.Errorf("Expected allow_all to allow everything, got %v, %q", ok, reason)
+	}
+	if err := (&QueryFilter{AllowAll: true, Owners: []string{"alice"}}).Validate(); err == nil {
+		t.Error("Expected error for allow_all with owners")
+	}
+}
+
+func TestQueryFilterMatchStarredQueryWithoutDataset(t *testing.T) {
+	filter := QueryFilter{Datasets: []string{"logs-*"}}
+
+	// Queries created by the API may only name their dataset in the APL
+	sq := axiom.StarredQuery{Name: "Errors", Kind: "apl", Query: axiom.StarredQueryContent{APL: "['logs-prod'] | take 1"}}
+	if ok, reason := filter.MatchStarredQuery(sq, ""); !ok {
+		t.Errorf("Expected the dataset of the APL to match, got %q", reason)
+	}
+
+	sq.Query.APL = "union ['logs-prod'], ['traces']"
+	if ok, reason := filter.MatchStarredQuery(sq, ""); ok || reason != "dataset is unknown, so it does not match datasets" {
+		t.Errorf("Expected an unknown dataset to be rejected, got %v, %q", ok, reason)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	_ "embed"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/roessland/curated-axiom-mcp/pkg/utils/iferr"
@@ -17,13 +19,23 @@ var embeddedConfigTemplate string
 //go:embed embedded_queries.yaml
 var embeddedQueriesTemplate string
 
+// profileNameRegex matches valid profile names
+var profileNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
 const (
 	configDirName  = "curated-axiom-mcp"
 	configFileName = "config"
 )
 
+// Flags holds command line flags that override configuration values
+type Flags struct {
+	Port      int    // --port
+	RecordDir string // --record
+	ReplayDir string // --replay
+}
+
 // LoadConfig loads configuration with precedence: CLI flags > Env vars > Config file > Defaults
-func LoadConfig(configFile string, portFlag int) (*AppConfig, error) {
+func LoadConfig(configFile string, flags Flags) (*AppConfig, error) {
 	v := viper.New()
 
 	// Set defaults
@@ -66,12 +78,18 @@ func LoadConfig(configFile string, portFlag int) (*AppConfig, error) {
 	}
 
 	// Handle port precedence: PORT env > --port flag > config file > default
-	if portFlag > 0 {
+	if flags.Port > 0 {
 		// Flag was explicitly set, override config file but not env var
 		if os.Getenv("PORT") == "" {
-			v.Set("server.port", portFlag)
+			v.Set("server.port", flags.Port)
 		}
 	}
+	if flags.RecordDir != "" {
+		v.Set("axiom.record_dir", flags.RecordDir)
+	}
+	if flags.ReplayDir != "" {
+		v.Set("axiom.replay_dir", flags.ReplayDir)
+	}
 
 	// Unmarshal into struct
 	var config AppConfig
@@ -79,6 +97,13 @@ func LoadConfig(configFile string, portFlag int) (*AppConfig, error) {
 		return nil, fmt.Errorf("error unmarshaling config: %w", err)
 	}
 
+	// Profile tokens can be kept out of the config file
+	for i, profile := range config.Profiles {
+		if profile.Token == "" {
+			config.Profiles[i].Token = os.Getenv(profileTokenEnv(profile.Name))
+		}
+	}
+
 	// Validate required fields
 	if err := validateConfig(&config); err != nil {
 		return nil, err
@@ -99,10 +124,22 @@ func setDefaults(v *viper.Viper) {
 		v.SetDefault("queries.file", "queries.yaml")
 	}
 	v.SetDefault("queries.cache_ttl", "5m")
+	v.SetDefault("queries.timeout", "60s")
+	v.SetDefault("queries.max_concurrent", 4)
+	v.SetDefault("queries.result_cache_ttl", "5m")
+	v.SetDefault("queries.result_cache_relative_ttl", "30s")
+	v.SetDefault("queries.result_cache_max_mb", 64)
+	v.SetDefault("queries.page_ttl", "10m")
 	v.SetDefault("logging.level", "debug")
 	v.SetDefault("logging.format", "text")
 }
 
+// profileTokenEnv returns the environment variable holding a profile's token,
+// e.g. AXIOM_TOKEN_PROD for the prod profile
+func profileTokenEnv(name string) string {
+	return "AXIOM_TOKEN_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
 func getConfigDir() string {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -112,12 +149,55 @@ func getConfigDir() string {
 }
 
 func validateConfig(config *AppConfig) error {
-	if config.Axiom.Token == "" {
+	if config.Axiom.RecordDir != "" && config.Axiom.ReplayDir != "" {
+		return fmt.Errorf("--record and --replay cannot be used together")
+	}
+	for _, pattern := range config.Datasets.Allow {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid dataset pattern %q in datasets.allow: %w", pattern, err)
+		}
+	}
+	if err := config.Axiom.Filter.Validate(); err != nil {
+		return fmt.Errorf("invalid axiom.filter: %w", err)
+	}
+	if len(config.Profiles) > 0 {
+		return validateProfiles(config)
+	}
+	// Replay mode serves responses from cassettes and never talks to Axiom
+	if config.Axiom.Token == "" && config.Axiom.ReplayDir == "" {
 		return fmt.Errorf("AXIOM_TOKEN is required (set via environment variable or config file)")
 	}
 	return nil
 }
 
+// validateProfiles checks that every profile has a unique name that can be
+// used as a tool name prefix, and a token unless replaying
+func validateProfiles(config *AppConfig) error {
+	seen := make(map[string]bool)
+	for i, profile := range config.Profiles {
+		if profile.Name == "" {
+			return fmt.Errorf("profile %d: name is required", i)
+		}
+		if !profileNameRegex.MatchString(profile.Name) {
+			return fmt.Errorf("profile %s: name may only contain letters, digits, '_' and '-'", profile.Name)
+		}
+		if seen[profile.Name] {
+			return fmt.Errorf("profile %s: duplicate name", profile.Name)
+		}
+		seen[profile.Name] = true
+
+		if err := profile.Filter.Validate(); err != nil {
+			return fmt.Errorf("profile %s: invalid filter: %w", profile.Name, err)
+		}
+
+		if profile.Token == "" && config.Axiom.ReplayDir == "" {
+			return fmt.Errorf("profile %s: token is required (set %s or token in the config file)",
+				profile.Name, profileTokenEnv(profile.Name))
+		}
+	}
+	return nil
+}
+
 // CreateExampleConfig creates an example config file and queries file
 func CreateExampleConfig() error {
 	configDir := getConfigDir()
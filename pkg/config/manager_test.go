@@ -0,0 +1,131 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes a config file to a temporary directory
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("AXIOM_TOKEN", "")
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigProfiles(t *testing.T) {
+	path := writeConfig(t, `
+profiles:
+  - name: prod
+    token: xaat-prod
+  - name: eu-prod
+    url: https://api.eu.axiom.co
+    filter:
+      owners: [eu-team]
+  - name: staging
+    token: xaat-staging
+    filter:
+      allow_all: true
+axiom:
+  filter:
+    owners: [alice]
+    exclude: ["*_draft"]
+`)
+	t.Setenv("AXIOM_TOKEN_EU_PROD", "xaat-eu")
+
+	appConfig, err := LoadConfig(path, Flags{RecordDir: "cassettes"})
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	profiles := appConfig.AxiomProfiles()
+	if len(profiles) != 3 {
+		t.Fatalf("Expected 3 profiles, got %d", len(profiles))
+	}
+	if profiles[0].URL != "https://api.axiom.co" {
+		t.Errorf("Expected prod to inherit the default URL, got %s", profiles[0].URL)
+	}
+	if len(profiles[0].Filter.Owners) != 1 || profiles[0].Filter.Owners[0] != "alice" || len(profiles[0].Filter.Exclude) != 1 {
+		t.Errorf("Expected prod to inherit the axiom filter, got %+v", profiles[0].Filter)
+	}
+	if len(profiles[1].Filter.Owners) != 1 || profiles[1].Filter.Owners[0] != "eu-team" || len(profiles[1].Filter.Exclude) != 0 {
+		t.Errorf("Expected eu-prod to keep its own filter, got %+v", profiles[1].Filter)
+	}
+	if !profiles[2].Filter.AllowAll || len(profiles[2].Filter.Owners) != 0 {
+		t.Errorf("Expected staging to opt out of the axiom filter, got %+v", profiles[2].Filter)
+	}
+	if profiles[1].Token != "xaat-eu" {
+		t.Errorf("Expected eu-prod token from environment, got %q", profiles[1].Token)
+	}
+	if profiles[1].RecordDir != filepath.Join("cassettes", "eu-prod") {
+		t.Errorf("Expected per-profile record directory, got %s", profiles[1].RecordDir)
+	}
+}
+
+func TestLoadConfigSingleProfile(t *testing.T) {
+	path := writeConfig(t, `
+axiom:
+  token: xaat-single
+`)
+
+	appConfig, err := LoadConfig(path, Flags{})
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	profiles := appConfig.AxiomProfiles()
+	if len(profiles) != 1 || profiles[0].Name != DefaultProfileName || profiles[0].Token != "xaat-single" {
+		t.Errorf("Expected the axiom section as the default profile, got %+v", profiles)
+	}
+}
+
+func TestLoadConfigInvalidProfiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{
+			name:    "missing name",
+			config:  "profiles:\n  - token: xaat-a\n",
+			wantErr: "name is required",
+		},
+		{
+			name:    "invalid name",
+			config:  "profiles:\n  - name: prod.eu\n    token: xaat-a\n",
+			wantErr: "may only contain",
+		},
+		{
+			name:    "duplicate name",
+			config:  "profiles:\n  - name: prod\n    token: xaat-a\n  - name: prod\n    token: xaat-b\n",
+			wantErr: "duplicate name",
+		},
+		{
+			name:    "invalid filter pattern",
+			config:  "profiles:\n  - name: prod\n    token: xaat-a\n    filter:\n      include: [\"[\"]\n",
+			wantErr: "invalid filter",
+		},
+		{
+			name:    "missing token",
+			config:  "profiles:\n  - name: staging\n",
+			wantErr: "AXIOM_TOKEN_STAGING",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, tt.config)
+			_, err := LoadConfig(path, Flags{})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
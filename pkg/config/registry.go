@@ -2,8 +2,10 @@ package config
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -12,6 +14,23 @@ import (
 	"github.com/roessland/curated-axiom-mcp/pkg/caxiom"
 )
 
+// MaxToolNameLength is the longest tool name MCP clients accept
+const MaxToolNameLength = 64
+
+// toolNameRegex matches the tool names MCP clients and model APIs accept
+var toolNameRegex = regexp.MustCompile(fmt.Sprintf(`^[A-Za-z0-9_-]{1,%d}$`, MaxToolNameLength))
+
+// ValidToolName reports whether MCP clients accept a tool name
+func ValidToolName(name string) bool {
+	return toolNameRegex.MatchString(name)
+}
+
+// ProfileToolName returns the name of a curated tool of a profile when
+// several profiles are loaded, e.g. prod_entity_data
+func ProfileToolName(profile, toolName string) string {
+	return profile + "_" + toolName
+}
+
 // Registry holds the loaded queries and provides thread-safe access
 type Registry struct {
 	queries        *QueryRegistry
@@ -19,10 +38,18 @@ type Registry struct {
 	loadedAt       time.Time
 	cacheTTL       time.Duration
 	filePath       string
-	axiomClient    *axiom.Client
+	profiles       []registryProfile
 	mu             sync.RWMutex
 }
 
+// registryProfile is an Axiom organization whose starred queries are loaded
+type registryProfile struct {
+	name         string
+	clientConfig *axiom.AxiomConfig
+	client       *axiom.Client // Created from clientConfig on first load, unless set by SetClient
+	filter       QueryFilter
+}
+
 // NewRegistry creates a new query registry
 func NewRegistry(filePath string, cacheTTL time.Duration) *Registry {
 	return &Registry{
@@ -34,19 +61,43 @@ func NewRegistry(filePath string, cacheTTL time.Duration) *Registry {
 
 // NewRegistryWithAxiom creates a new registry with Axiom client for dynamic loading
 func NewRegistryWithAxiom(axiomConfig *AxiomConfig, cacheTTL time.Duration) *Registry {
-	// Convert config to avoid import cycle
-	clientConfig := &axiom.AxiomConfig{
-		Token:   axiomConfig.Token,
-		OrgID:   axiomConfig.OrgID,
-		Dataset: axiomConfig.Dataset,
-		URL:     axiomConfig.URL,
+	profile := *axiomConfig
+	if profile.Name == "" {
+		profile.Name = DefaultProfileName
 	}
-	
-	return &Registry{
+	return NewRegistryWithProfiles([]AxiomConfig{profile}, cacheTTL)
+}
+
+// NewRegistryWithProfiles creates a new registry loading starred queries from
+// every profile. With more than one profile, tool names are prefixed with the
+// profile name, e.g. prod_entity_data.
+func NewRegistryWithProfiles(profiles []AxiomConfig, cacheTTL time.Duration) *Registry {
+	r := &Registry{
 		cacheTTL:       cacheTTL,
-		axiomClient:    axiom.NewClient(clientConfig),
 		dynamicQueries: make(map[string]*DynamicQuery),
 	}
+	for _, profile := range profiles {
+		r.profiles = append(r.profiles, registryProfile{
+			name:         profile.Name,
+			clientConfig: profile.ClientConfig(),
+			filter:       profile.Filter,
+		})
+	}
+	return r
+}
+
+// SetClient makes the registry load the starred queries of a profile with
+// client, so it shares the connections and concurrency limit of the server's
+// tool calls. Unknown profiles are ignored.
+func (r *Registry) SetClient(profileName string, client *axiom.Client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.profiles {
+		if r.profiles[i].name == profileName {
+			r.profiles[i].client = client
+		}
+	}
 }
 
 // Load loads or reloads the queries from the file
@@ -111,7 +162,7 @@ func (r *Registry) ListQueries() (map[string]string, error) {
 
 // LoadFromAxiom loads queries from Axiom starred queries with 10-second timeout
 func (r *Registry) LoadFromAxiom() error {
-	if r.axiomClient == nil {
+	if len(r.profiles) == 0 {
 		return fmt.Errorf("no Axiom client configured")
 	}
 
@@ -122,7 +173,7 @@ func (r *Registry) LoadFromAxiom() error {
 	// Run the loading in a goroutine to respect timeout
 	errChan := make(chan error, 1)
 	go func() {
-		errChan <- r.loadFromAxiomInternal()
+		errChan <- r.loadFromAxiomInternal(ctx)
 	}()
 
 	select {
@@ -134,23 +185,65 @@ func (r *Registry) LoadFromAxiom() error {
 }
 
 // loadFromAxiomInternal performs the actual loading logic
-func (r *Registry) loadFromAxiomInternal() error {
+func (r *Registry) loadFromAxiomInternal(ctx context.Context) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	slog.Info("Fetching starred queries from Axiom...")
-	// Fetch starred queries from Axiom
-	starredQueries, err := r.axiomClient.StarredQueries()
-	if err != nil {
-		slog.Error("Failed to fetch starred queries from Axiom", "error", err)
-		return fmt.Errorf("failed to fetch starred queries from Axiom: %w", err)
+	// Fetch starred queries from all profiles at once, so a slow
+	// organization doesn't use up the timeout of the others
+	starredQueries := make([][]axiom.StarredQuery, len(r.profiles))
+	errs := make([]error, len(r.profiles))
+	var wg sync.WaitGroup
+	for i := range r.profiles {
+		if r.profiles[i].client == nil {
+			r.profiles[i].client = axiom.NewClient(r.profiles[i].clientConfig)
+		}
+		profile := r.profiles[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slog.Info("Fetching starred queries from Axiom...", "profile", profile.name)
+			starredQueries[i], errs[i] = profile.client.StarredQueries(ctx)
+		}()
 	}
-	slog.Info("Fetched starred queries from Axiom", "count", len(starredQueries))
+	wg.Wait()
 
 	// Clear existing dynamic queries
 	r.dynamicQueries = make(map[string]*DynamicQuery)
 
-	// Parse each starred query
+	failed := 0
+	total := 0
+	for i, profile := range r.profiles {
+		if errs[i] != nil {
+			slog.Error("Failed to fetch starred queries from Axiom", "profile", profile.name, "error", errs[i])
+			failed++
+			continue
+		}
+		slog.Info("Fetched starred queries from Axiom", "profile", profile.name, "count", len(starredQueries[i]))
+		total += len(starredQueries[i])
+		r.addStarredQueries(profile, starredQueries[i])
+	}
+
+	// Serve the profiles that did load, unless none of them did
+	if failed == len(r.profiles) {
+		return fmt.Errorf("failed to fetch starred queries from Axiom: %w", errors.Join(errs...))
+	}
+
+	r.loadedAt = time.Now()
+	
+	if len(r.dynamicQueries) == 0 {
+		slog.Warn("No CuratedAxiomMCP queries found in starred queries", "total_starred_queries", total)
+	} else {
+		slog.Info("Successfully loaded dynamic queries from Axiom", "count", len(r.dynamicQueries))
+	}
+	
+	return nil
+}
+
+// addStarredQueries parses the starred queries of a profile and adds the
+// curated ones that pass the profile's filter as dynamic queries
+func (r *Registry) addStarredQueries(profile registryProfile, starredQueries []axiom.StarredQuery) {
+	profileName := profile.name
 	for _, sq := range starredQueries {
 		// Try to parse the query for MCP usage
 		parsed, err := caxiom.ParseStarredQuery(sq.Name, sq.Query.APL)
@@ -158,33 +251,50 @@ func (r *Registry) loadFromAxiomInternal() error {
 			// Check if this query contains CuratedAxiomMCP marker but failed to parse
 			if strings.Contains(sq.Query.APL, "CuratedAxiomMCP") {
 				// This is a parsing error for a query that should be processed - log as error
-				slog.Error("Failed to parse CuratedAxiomMCP query", "name", sq.Name, "error", err, "full_apl", sq.Query.APL)
+				slog.Error("Failed to parse CuratedAxiomMCP query", "profile", profileName, "name", sq.Name, "error", err, "full_apl", sq.Query.APL)
 			} else {
 				// This query doesn't have the marker - log at debug level to reduce noise
-				slog.Debug("Skipping starred query (no CuratedAxiomMCP marker)", "name", sq.Name)
+				slog.Debug("Skipping starred query (no CuratedAxiomMCP marker)", "profile", profileName, "name", sq.Name)
 			}
 			continue
 		}
+		if len(parsed.Warnings) > 0 {
+			slog.Warn("Ignoring unknown fields in CuratedAxiomMCP metadata without a Version", "profile", profileName, "name", sq.Name, "warnings", parsed.Warnings)
+		}
+
+		included, reason := profile.filter.MatchStarredQuery(sq, parsed.Metadata.CuratedAxiomMCP.ToolName)
+		if !included {
+			slog.Info("Skipping starred query (filtered out)", "profile", profileName, "name", sq.Name, "owner", sq.Who, "reason", reason)
+			continue
+		}
 
 		// Convert to DynamicQuery
 		dynamicQuery := &DynamicQuery{
 			Name:        sq.Name,
+			Profile:     profileName,
 			OriginalAPL: parsed.OriginalAPL,
 			TemplateAPL: parsed.TemplateAPL,
 			ToolName:    parsed.Metadata.CuratedAxiomMCP.ToolName,
 			Description: parsed.Metadata.CuratedAxiomMCP.Description,
 			Constraints: parsed.Metadata.CuratedAxiomMCP.Constraints,
+			Examples:    parsed.Metadata.CuratedAxiomMCP.Examples,
+			Timeout:     parsed.Metadata.CuratedAxiomMCP.Timeout,
 			Parameters:  make([]DynamicParameter, len(parsed.Metadata.CuratedAxiomMCP.Params)),
 		}
 
 		// Convert parameters
 		for i, param := range parsed.Metadata.CuratedAxiomMCP.Params {
+			// Checked by ParseStarredQuery
+			paramType, _ := caxiom.ParseParamType(param.Type)
 			dynamicQuery.Parameters[i] = DynamicParameter{
 				Name:        param.Name,
-				Type:        param.Type,
+				Type:        string(paramType),
 				Example:     param.Example,
 				Description: param.Description,
-				Required:    true, // For now, all template parameters are required
+				Required:    param.IsRequired(),
+				Default:     param.Default,
+
+				ParamConstraints: param.ParamConstraints,
 			}
 		}
 
@@ -193,20 +303,19 @@ func (r *Registry) loadFromAxiomInternal() error {
 		if key == "" {
 			key = sq.Name
 		}
+		// Namespace tools by profile when several organizations are loaded
+		if len(r.profiles) > 1 {
+			key = ProfileToolName(profileName, key)
+		}
+		if !ValidToolName(key) {
+			slog.Error("Skipping curated query with a tool name MCP clients reject", "profile", profileName, "name", sq.Name, "tool_name", key,
+				"reason", fmt.Sprintf("tool names must be 1 to %d letters, digits, underscores or hyphens", MaxToolNameLength))
+			continue
+		}
 
 		r.dynamicQueries[key] = dynamicQuery
-		slog.Info("Loaded dynamic query", "name", sq.Name, "tool_name", key)
+		slog.Info("Loaded dynamic query", "profile", profileName, "name", sq.Name, "tool_name", key, "reason", reason)
 	}
-
-	r.loadedAt = time.Now()
-	
-	if len(r.dynamicQueries) == 0 {
-		slog.Warn("No CuratedAxiomMCP queries found in starred queries", "total_starred_queries", len(starredQueries))
-	} else {
-		slog.Info("Successfully loaded dynamic queries from Axiom", "count", len(r.dynamicQueries))
-	}
-	
-	return nil
 }
 
 // GetDynamicQuery retrieves a dynamic query by tool name
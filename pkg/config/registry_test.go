@@ -0,0 +1,195 @@
+package config
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom"
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom/axiomtest"
+)
+
+func newTestRegistry(t *testing.T, srv *axiomtest.Server) *Registry {
+	t.Helper()
+	return NewRegistryWithAxiom(&AxiomConfig{
+		Token: axiomtest.Token,
+		URL:   srv.URL,
+	}, 5*time.Minute)
+}
+
+func TestLoadFromAxiom(t *testing.T) {
+	srv := axiomtest.NewServer(t)
+	if err := srv.LoadFixtures(axiomtest.DefaultFixtures()); err != nil {
+		t.Fatalf("Failed to load fixtures: %v", err)
+	}
+
+	registry := newTestRegistry(t, srv)
+	if err := registry.LoadFromAxiom(); err != nil {
+		t.Fatalf("Failed to load from Axiom: %v", err)
+	}
+
+	queries := registry.ListDynamicQueries()
+	if len(queries) != 1 {
+		t.Fatalf("Expected 1 dynamic query, got %d", len(queries))
+	}
+
+	query, err := registry.GetDynamicQuery("entity_data")
+	if err != nil {
+		t.Fatalf("Failed to get dynamic query: %v", err)
+	}
+	if query.Name != "Entity data" {
+		t.Errorf("Expected name 'Entity data', got '%s'", query.Name)
+	}
+	if len(query.Parameters) != 3 {
+		t.Errorf("Expected 3 parameters, got %d", len(query.Parameters))
+	}
+	if len(query.Constraints) != 1 {
+		t.Errorf("Expected 1 constraint, got %d", len(query.Constraints))
+	}
+}
+
+func TestLoadFromAxiomUnauthorized(t *testing.T) {
+	srv := axiomtest.NewServer(t)
+	srv.InjectFault(axiomtest.StarredQueriesPath, axiomtest.Fault{Status: http.StatusUnauthorized})
+
+	registry := newTestRegistry(t, srv)
+	if err := registry.LoadFromAxiom(); err == nil {
+		t.Error("Expected error when Axiom rejects the token")
+	}
+}
+
+func TestLoadFromAxiomProfiles(t *testing.T) {
+	prod := axiomtest.NewServer(t)
+	if err := prod.LoadFixtures(axiomtest.DefaultFixtures()); err != nil {
+		t.Fatalf("Failed to load fixtures: %v", err)
+	}
+	staging := axiomtest.NewServer(t)
+	if err := staging.LoadFixtures(axiomtest.DefaultFixtures()); err != nil {
+		t.Fatalf("Failed to load fixtures: %v", err)
+	}
+
+	registry := NewRegistryWithProfiles([]AxiomConfig{
+		{Name: "prod", Token: axiomtest.Token, URL: prod.URL},
+		{Name: "staging", Token: axiomtest.Token, URL: staging.URL},
+	}, 5*time.Minute)
+	if err := registry.LoadFromAxiom(); err != nil {
+		t.Fatalf("Failed to load from Axiom: %v", err)
+	}
+
+	queries := registry.ListDynamicQueries()
+	if len(queries) != 2 {
+		t.Fatalf("Expected 2 dynamic queries, got %d", len(queries))
+	}
+	for _, profile := range []string{"prod", "staging"} {
+		query, err := registry.GetDynamicQuery(profile + "_entity_data")
+		if err != nil {
+			t.Fatalf("Failed to get dynamic query: %v", err)
+		}
+		if query.Profile != profile {
+			t.Errorf("Expected profile %s, got %s", profile, query.Profile)
+		}
+	}
+}
+
+func TestLoadFromAxiomProfilesPartialFailure(t *testing.T) {
+	prod := axiomtest.NewServer(t)
+	if err := prod.LoadFixtures(axiomtest.DefaultFixtures()); err != nil {
+		t.Fatalf("Failed to load fixtures: %v", err)
+	}
+	staging := axiomtest.NewServer(t)
+	staging.InjectFault(axiomtest.StarredQueriesPath, axiomtest.Fault{Status: http.StatusUnauthorized})
+
+	registry := NewRegistryWithProfiles([]AxiomConfig{
+		{Name: "prod", Token: axiomtest.Token, URL: prod.URL},
+		{Name: "staging", Token: axiomtest.Token, URL: staging.URL},
+	}, 5*time.Minute)
+	if err := registry.LoadFromAxiom(); err != nil {
+		t.Fatalf("Expected profiles that loaded to be served, got %v", err)
+	}
+
+	if _, err := registry.GetDynamicQuery("prod_entity_data"); err != nil {
+		t.Errorf("Expected prod query to be loaded: %v", err)
+	}
+	if len(registry.ListDynamicQueries()) != 1 {
+		t.Errorf("Expected only prod queries, got %d", len(registry.ListDynamicQueries()))
+	}
+}
+
+func TestLoadFromAxiomFilter(t *testing.T) {
+	srv := axiomtest.NewServer(t)
+	curated := func(toolName string) string {
+		return "['events'] | limit 10\n\n// CuratedAxiomMCP:\n//   ToolName: " + toolName + "\n//   Description: Test query"
+	}
+	srv.SetStarredQueries([]axiom.StarredQuery{
+		{ID: "1", Name: "Mine", Who: "alice", Dataset: "events", Kind: "apl", Query: axiom.StarredQueryContent{APL: curated("mine")}},
+		{ID: "2", Name: "Theirs", Who: "bob", Dataset: "events", Kind: "apl", Query: axiom.StarredQueryContent{APL: curated("theirs")}},
+		{ID: "3", Name: "Draft", Who: "alice", Dataset: "events", Kind: "apl", Query: axiom.StarredQueryContent{APL: curated("draft_mine")}},
+	})
+
+	registry := NewRegistryWithAxiom(&AxiomConfig{
+		Token: axiomtest.Token,
+		URL:   srv.URL,
+		Filter: QueryFilter{
+			Owners:  []string{"alice"},
+			Exclude: []string{"draft_*"},
+		},
+	}, 5*time.Minute)
+	if err := registry.LoadFromAxiom(); err != nil {
+		t.Fatalf("Failed to load from Axiom: %v", err)
+	}
+
+	queries := registry.ListDynamicQueries()
+	if len(queries) != 1 || queries["mine"] == nil {
+		t.Errorf("Expected only the mine tool, got %v", queries)
+	}
+}
+
+func TestLoadFromAxiomProfilesToolNameTooLong(t *testing.T) {
+	curated := func(toolName string) string {
+		return "['events'] | limit 10\n\n// CuratedAxiomMCP:\n//   ToolName: " + toolName + "\n//   Description: Test query"
+	}
+	// Fits on its own, but not once prefixed with the profile name
+	long := strings.Repeat("x", MaxToolNameLength-2)
+	prod := axiomtest.NewServer(t)
+	prod.SetStarredQueries([]axiom.StarredQuery{
+		{ID: "1", Name: "Short", Kind: "apl", Query: axiom.StarredQueryContent{APL: curated("short")}},
+		{ID: "2", Name: "Long", Kind: "apl", Query: axiom.StarredQueryContent{APL: curated(long)}},
+	})
+	staging := axiomtest.NewServer(t)
+
+	registry := NewRegistryWithProfiles([]AxiomConfig{
+		{Name: "prod", Token: axiomtest.Token, URL: prod.URL},
+		{Name: "staging", Token: axiomtest.Token, URL: staging.URL},
+	}, 5*time.Minute)
+	if err := registry.LoadFromAxiom(); err != nil {
+		t.Fatalf("Failed to load from Axiom: %v", err)
+	}
+
+	queries := registry.ListDynamicQueries()
+	if len(queries) != 1 || queries["prod_short"] == nil {
+		t.Errorf("Expected only the prod_short tool, got %v", queries)
+	}
+}
+
+func TestLoadFromAxiomSetClient(t *testing.T) {
+	configured := axiomtest.NewServer(t)
+	shared := axiomtest.NewServer(t)
+	if err := shared.LoadFixtures(axiomtest.DefaultFixtures()); err != nil {
+		t.Fatalf("Failed to load fixtures: %v", err)
+	}
+
+	registry := NewRegistryWithAxiom(&AxiomConfig{Token: axiomtest.Token, URL: configured.URL}, 5*time.Minute)
+	registry.SetClient(DefaultProfileName, shared.Client())
+	if err := registry.LoadFromAxiom(); err != nil {
+		t.Fatalf("Failed to load from Axiom: %v", err)
+	}
+
+	if len(configured.Requests()) != 0 || len(shared.Requests()) != 1 {
+		t.Errorf("Expected starred queries to be fetched with the shared client, got %d and %d requests",
+			len(configured.Requests()), len(shared.Requests()))
+	}
+	if _, err := registry.GetDynamicQuery("entity_data"); err != nil {
+		t.Errorf("Expected the query of the shared client's server: %v", err)
+	}
+}
@@ -1,20 +1,86 @@
 package config
 
-import "time"
+import (
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom"
+	"github.com/roessland/curated-axiom-mcp/pkg/caxiom"
+)
 
 // AppConfig represents the application configuration
 type AppConfig struct {
-	Axiom   AxiomConfig   `yaml:"axiom" mapstructure:"axiom"`
-	Server  ServerConfig  `yaml:"server" mapstructure:"server"`
-	Queries QueriesConfig `yaml:"queries" mapstructure:"queries"`
-	Logging LoggingConfig `yaml:"logging" mapstructure:"logging"`
+	Axiom    AxiomConfig    `yaml:"axiom" mapstructure:"axiom"`
+	Profiles []AxiomConfig  `yaml:"profiles,omitempty" mapstructure:"profiles"` // Named Axiom organizations, replaces axiom when set
+	Server   ServerConfig   `yaml:"server" mapstructure:"server"`
+	Queries  QueriesConfig  `yaml:"queries" mapstructure:"queries"`
+	Datasets DatasetsConfig `yaml:"datasets" mapstructure:"datasets"`
+	Logging  LoggingConfig  `yaml:"logging" mapstructure:"logging"`
 }
 
 type AxiomConfig struct {
-	Token   string `yaml:"token" mapstructure:"token"`
-	OrgID   string `yaml:"org_id" mapstructure:"org_id"`
-	Dataset string `yaml:"dataset" mapstructure:"dataset"`
-	URL     string `yaml:"url" mapstructure:"url"`
+	Name      string `yaml:"name,omitempty" mapstructure:"name"` // Profile name, used to namespace tools
+	Token     string `yaml:"token" mapstructure:"token"`
+	OrgID     string `yaml:"org_id" mapstructure:"org_id"`
+	Dataset   string `yaml:"dataset" mapstructure:"dataset"`
+	URL       string `yaml:"url" mapstructure:"url"`
+	RecordDir string `yaml:"record_dir,omitempty" mapstructure:"record_dir"` // Set by --record
+	ReplayDir string `yaml:"replay_dir,omitempty" mapstructure:"replay_dir"` // Set by --replay
+
+	Filter QueryFilter `yaml:"filter,omitempty" mapstructure:"filter"` // Which curated starred queries become tools
+}
+
+// ClientConfig converts the config to an axiom.AxiomConfig
+func (c *AxiomConfig) ClientConfig() *axiom.AxiomConfig {
+	return &axiom.AxiomConfig{
+		Token:     c.Token,
+		OrgID:     c.OrgID,
+		Dataset:   c.Dataset,
+		URL:       c.URL,
+		RecordDir: c.RecordDir,
+		ReplayDir: c.ReplayDir,
+	}
+}
+
+// DefaultProfileName is the name of the profile configured by the axiom section
+const DefaultProfileName = "default"
+
+// AxiomProfiles returns the configured Axiom profiles. Without a profiles
+// list, the axiom section is the only profile. The first profile is the
+// default for tools that take a profile argument.
+//
+// Profiles without a filter use the filter of the axiom section.
+//
+// Record and replay directories set on the axiom section apply to every
+// profile. With several profiles each one gets its own subdirectory, since
+// the same query may return different results in different organizations.
+func (c *AppConfig) AxiomProfiles() []AxiomConfig {
+	if len(c.Profiles) == 0 {
+		profile := c.Axiom
+		if profile.Name == "" {
+			profile.Name = DefaultProfileName
+		}
+		return []AxiomConfig{profile}
+	}
+
+	profiles := make([]AxiomConfig, len(c.Profiles))
+	for i, profile := range c.Profiles {
+		if profile.URL == "" {
+			profile.URL = c.Axiom.URL
+		}
+		if profile.Filter.IsZero() {
+			profile.Filter = c.Axiom.Filter
+		}
+		if c.Axiom.RecordDir != "" {
+			profile.RecordDir = filepath.Join(c.Axiom.RecordDir, profile.Name)
+		}
+		if c.Axiom.ReplayDir != "" {
+			profile.ReplayDir = filepath.Join(c.Axiom.ReplayDir, profile.Name)
+		}
+		profiles[i] = profile
+	}
+	return profiles
 }
 
 type ServerConfig struct {
@@ -25,6 +91,35 @@ type ServerConfig struct {
 type QueriesConfig struct {
 	File     string        `yaml:"file" mapstructure:"file"`
 	CacheTTL time.Duration `yaml:"cache_ttl" mapstructure:"cache_ttl"`
+	Timeout  time.Duration `yaml:"timeout" mapstructure:"timeout"` // Default timeout for query execution
+
+	MaxConcurrent int `yaml:"max_concurrent" mapstructure:"max_concurrent"` // Maximum number of concurrent Axiom queries
+
+	ResultCacheTTL         time.Duration `yaml:"result_cache_ttl" mapstructure:"result_cache_ttl"`                   // How long curated tool results are cached, 0 disables
+	ResultCacheRelativeTTL time.Duration `yaml:"result_cache_relative_ttl" mapstructure:"result_cache_relative_ttl"` // Shorter TTL for queries using ago()/now()
+	ResultCacheMaxMB       int           `yaml:"result_cache_max_mb" mapstructure:"result_cache_max_mb"`             // Memory bound of the result cache
+
+	PageTTL time.Duration `yaml:"page_ttl" mapstructure:"page_ttl"` // How long truncated results can be paged with fetch_more, 0 disables
+}
+
+// DatasetsConfig controls which datasets the dataset discovery tools expose.
+// It does not restrict the datasets run_query and curated tools can query,
+// use the permissions of the Axiom token for that.
+type DatasetsConfig struct {
+	Allow []string `yaml:"allow" mapstructure:"allow"` // Glob patterns of exposed datasets, empty exposes all
+}
+
+// Allowed reports whether the dataset may be exposed to the LLM
+func (c *DatasetsConfig) Allowed(name string) bool {
+	if len(c.Allow) == 0 {
+		return true
+	}
+	for _, pattern := range c.Allow {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
 }
 
 type LoggingConfig struct {
@@ -61,20 +156,26 @@ type Parameter struct {
 
 // DynamicQuery represents a query parsed from Axiom starred queries
 type DynamicQuery struct {
-	Name         string
-	OriginalAPL  string
-	TemplateAPL  string
-	ToolName     string
-	Parameters   []DynamicParameter
-	Constraints  []string
-	Description  string
+	Name        string
+	Profile     string // Name of the Axiom profile the query was loaded from
+	OriginalAPL string
+	TemplateAPL string
+	ToolName    string
+	Parameters  []DynamicParameter
+	Constraints []caxiom.Constraint
+	Examples    []caxiom.Example
+	Description string
+	Timeout     time.Duration // Per-tool query timeout, zero means use the default
 }
 
 // DynamicParameter represents a parameter for dynamic queries
 type DynamicParameter struct {
 	Name        string
-	Type        string
+	Type        string // Canonical type, see caxiom.ParamType
 	Example     string
 	Description string
-	Required    bool // Derived from template analysis
+	Required    bool
+	Default     string // Value used when an optional parameter is not given
+
+	caxiom.ParamConstraints
 }
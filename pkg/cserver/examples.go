@@ -0,0 +1,107 @@
+package cserver
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom/cassette"
+	"github.com/roessland/curated-axiom-mcp/pkg/caxiom"
+	"github.com/roessland/curated-axiom-mcp/pkg/config"
+)
+
+// ExampleResult is the outcome of running an example call of a dynamic tool
+type ExampleResult struct {
+	ToolName string
+	Number   int // Position of the example in the metadata, starting at 1
+	Example  caxiom.Example
+	APL      string   // Rendered query, empty if the arguments are invalid
+	Columns  []string // Columns of the result
+	Rows     int      // Number of rows of the result
+	Err      error    // Nil if the example passed
+}
+
+// TestExamples loads the dynamic tools and runs their example calls like an
+// agent would, checking each result against the expectations of its example.
+// Results are sorted by tool name, then by example.
+func (m *MCPManager) TestExamples(ctx context.Context) ([]ExampleResult, error) {
+	if err := m.registry.LoadFromAxiom(); err != nil {
+		return nil, fmt.Errorf("failed to load queries from Axiom: %w", err)
+	}
+	now, err := m.exampleClock()
+	if err != nil {
+		return nil, err
+	}
+
+	queries := m.registry.ListDynamicQueries()
+	toolNames := make([]string, 0, len(queries))
+	for toolName := range queries {
+		toolNames = append(toolNames, toolName)
+	}
+	sort.Strings(toolNames)
+
+	var results []ExampleResult
+	for _, toolName := range toolNames {
+		query := queries[toolName]
+		for i, example := range query.Examples {
+			result := ExampleResult{ToolName: toolName, Number: i + 1, Example: example}
+			result.Err = m.runExample(ctx, query, example, now, &result)
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// exampleClock returns the time relative times of examples are resolved
+// against. Recordings store it, so replaying them renders the same APL.
+func (m *MCPManager) exampleClock() (time.Time, error) {
+	axiomConfig := m.appConfig.Axiom
+	switch {
+	case axiomConfig.ReplayDir != "":
+		now, ok, err := cassette.LoadClock(axiomConfig.ReplayDir)
+		if err != nil || ok {
+			return now, err
+		}
+	case axiomConfig.RecordDir != "":
+		now := m.now()
+		return now, cassette.SaveClock(axiomConfig.RecordDir, now)
+	}
+	return m.now(), nil
+}
+
+// runExample renders and runs an example call and checks its result
+func (m *MCPManager) runExample(ctx context.Context, query *config.DynamicQuery, example caxiom.Example, now time.Time, result *ExampleResult) error {
+	profile, err := m.profiles.Get(query.Profile)
+	if err != nil {
+		return err
+	}
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = example.Params
+	renderedAPL, _, err := renderDynamicQuery(query, request, now)
+	if err != nil {
+		return err
+	}
+	result.APL = renderedAPL
+
+	timeout := queryTimeout(m.appConfig, query.Timeout)
+	queryCtx, cancel := withQueryTimeout(ctx, timeout)
+	defer cancel()
+	queryResult, err := profile.Client.ExecuteQuery(queryCtx, renderedAPL)
+	if err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+
+	if len(queryResult.Tables) > 0 {
+		table := queryResult.Tables[0]
+		for _, field := range table.Fields {
+			result.Columns = append(result.Columns, field.Name)
+		}
+		if len(table.Columns) > 0 {
+			result.Rows = len(table.Columns[0])
+		}
+	}
+	return example.Check(result.Columns, result.Rows)
+}
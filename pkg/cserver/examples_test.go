@@ -0,0 +1,100 @@
+package cserver
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom/axiomtest"
+	"github.com/roessland/curated-axiom-mcp/pkg/config"
+)
+
+const examplesAPL = `['entity_events']
+| where _time > datetime({{.StartTime}}) and id == '{{.EntityId}}'
+| limit 100
+
+// CuratedAxiomMCP:
+//   Version: 1
+//   ToolName: entity_events
+//   Params:
+//     - Name: EntityId
+//     - Name: StartTime
+//       Type: datetime
+//   Constraints:
+//     - Kind: not-in-future
+//       Param: StartTime
+//   Examples:
+//     - Description: matches the fixture
+//       Params: {EntityId: abc, StartTime: 2025-06-25T00:00:00Z}
+//       MinRows: 1
+//       MaxRows: 10
+//       Columns: [_time, id]
+//     - Params: {EntityId: abc, StartTime: now-1h}
+//       MaxRows: 1
+//       Columns: [msg]
+//     - Params: {EntityId: abc, StartTime: now+1h}`
+
+func TestTestExamples(t *testing.T) {
+	manager, srv := newTestManager(t)
+	srv.AddStarredQuery("Entity events", examplesAPL)
+	srv.SetResultContaining("['entity_events']", axiomtest.Result{
+		Fields: []query.Field{{Name: "_time", Type: "datetime"}, {Name: "id", Type: "string"}},
+		Rows:   [][]any{{"2025-06-25T01:00:00Z", "abc"}, {"2025-06-25T02:00:00Z", "abc"}},
+	})
+
+	results, err := manager.TestExamples(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to test examples: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 example results, got %+v", results)
+	}
+
+	if results[0].Err != nil || results[0].Rows != 2 || !strings.Contains(results[0].APL, "datetime(2025-06-25T00:00:00Z)") {
+		t.Errorf("Expected the first example to pass, got %+v", results[0])
+	}
+	if err := results[1].Err; err == nil || err.Error() != "expected at most 1 rows, got 2; missing columns msg, got _time, id" {
+		t.Errorf("Expected the second example to fail its expectations, got %v", err)
+	}
+	if err := results[2].Err; err == nil || !strings.Contains(err.Error(), "must not be in the future") || results[2].APL != "" {
+		t.Errorf("Expected the third example to violate the constraint, got %+v", results[2])
+	}
+}
+
+func TestTestExamplesRecordThenReplay(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	srv := axiomtest.NewServer(t)
+	srv.AddStarredQuery("Entity events", strings.Replace(examplesAPL, "StartTime: now-1h}\n//       MaxRows: 1\n//       Columns: [msg]", "StartTime: now-1h}", 1))
+	srv.SetDefaultResult(axiomtest.Result{})
+
+	run := func(axiomConfig config.AxiomConfig, now time.Time) []ExampleResult {
+		t.Helper()
+		appConfig := &config.AppConfig{
+			Axiom:   axiomConfig,
+			Queries: config.QueriesConfig{CacheTTL: 5 * time.Minute, Timeout: 5 * time.Second},
+		}
+		registry := config.NewRegistryWithAxiom(&appConfig.Axiom, appConfig.Queries.CacheTTL)
+		manager := NewMCP(appConfig, registry)
+		manager.now = func() time.Time { return now }
+		results, err := manager.TestExamples(context.Background())
+		if err != nil {
+			t.Fatalf("Failed to test examples: %v", err)
+		}
+		return results
+	}
+
+	recordedAt := time.Date(2025, 6, 26, 12, 30, 0, 0, time.UTC)
+	recorded := run(config.AxiomConfig{Token: axiomtest.Token, URL: srv.URL, RecordDir: dir}, recordedAt)
+	if recorded[1].Err != nil {
+		t.Fatalf("Expected the relative example to pass while recording, got %v", recorded[1].Err)
+	}
+
+	// Relative times resolve to the recording time, so the APL matches the cassette
+	replayed := run(config.AxiomConfig{ReplayDir: dir}, recordedAt.Add(time.Hour))
+	if replayed[1].Err != nil || replayed[1].APL != recorded[1].APL {
+		t.Errorf("Expected the relative example to replay %q, got %+v", recorded[1].APL, replayed[1])
+	}
+}
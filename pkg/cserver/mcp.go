@@ -3,10 +3,14 @@ package cserver
 import (
 	"fmt"
 	"log/slog"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/roessland/curated-axiom-mcp/pkg/caxiom"
 	"github.com/roessland/curated-axiom-mcp/pkg/config"
 )
 
@@ -14,6 +18,9 @@ type MCPManager struct {
 	server      *server.MCPServer
 	appConfig   *config.AppConfig
 	registry    *config.Registry
+	profiles    *Profiles        // Axiom clients and result caches, shared by all tool handlers
+	pages       *PageStore       // Truncated results retained for fetch_more
+	now         func() time.Time // Clock that relative times of examples resolve against
 	toolsLoaded bool
 	mu          sync.RWMutex
 }
@@ -21,14 +28,28 @@ type MCPManager struct {
 func NewMCP(appConfig *config.AppConfig, registry *config.Registry) *MCPManager {
 	s := server.NewMCPServer("curated-axiom-mcp", "1.0.0")
 
+	// Create long-lived clients so connections are reused and the
+	// concurrency limit applies across all tool calls
+	profiles := NewProfiles(appConfig)
+	pages := NewPageStore(appConfig.Queries.PageTTL)
+	for _, profile := range profiles.list {
+		registry.SetClient(profile.Name, profile.Client)
+	}
+
 	// Add static tools
-	s.AddTool(runQueryTool, RunQueryHandler(registry, appConfig))
-	s.AddTool(starredQueriesTool, DebugStarredQueriesHandler(appConfig))
+	s.AddTool(profiles.withProfileArgument(runQueryTool), RunQueryHandler(profiles, pages, appConfig))
+	s.AddTool(fetchMoreTool, FetchMoreHandler(pages))
+	s.AddTool(profiles.withProfileArgument(starredQueriesTool), DebugStarredQueriesHandler(profiles))
+	s.AddTool(profiles.withProfileArgument(listDatasetsTool), ListDatasetsHandler(profiles, appConfig))
+	s.AddTool(profiles.withProfileArgument(describeDatasetTool), DescribeDatasetHandler(profiles, appConfig))
 
 	manager := &MCPManager{
 		server:    s,
 		appConfig: appConfig,
 		registry:  registry,
+		profiles:  profiles,
+		pages:     pages,
+		now:       time.Now,
 	}
 
 	return manager
@@ -56,10 +77,15 @@ func (m *MCPManager) LoadDynamicTools() error {
 	// Register each dynamic query as an MCP tool
 	dynamicQueries := m.registry.ListDynamicQueries()
 	for toolName, query := range dynamicQueries {
+		profile, err := m.profiles.Get(query.Profile)
+		if err != nil {
+			slog.Error("Skipping dynamic tool", "name", toolName, "error", err)
+			continue
+		}
 		tool := createDynamicTool(toolName, query)
-		handler := CreateDynamicQueryHandler(toolName, m.registry, m.appConfig)
+		handler := CreateDynamicQueryHandler(toolName, m.registry, profile, m.pages, m.appConfig)
 		m.server.AddTool(tool, handler)
-		slog.Info("Registered dynamic tool", "name", toolName)
+		slog.Info("Registered dynamic tool", "name", toolName, "profile", profile.Name)
 	}
 
 	m.toolsLoaded = true
@@ -89,7 +115,7 @@ func (m *MCPManager) AreToolsLoaded() bool {
 func createDynamicTool(toolName string, query *config.DynamicQuery) mcp.Tool {
 	// Build options array for tool creation
 	opts := []mcp.ToolOption{
-		mcp.WithDescription(query.Description),
+		mcp.WithDescription(toolDescription(query)),
 		mcp.WithDestructiveHintAnnotation(false),
 		mcp.WithOpenWorldHintAnnotation(false),
 		mcp.WithIdempotentHintAnnotation(true),
@@ -98,12 +124,198 @@ func createDynamicTool(toolName string, query *config.DynamicQuery) mcp.Tool {
 
 	// Add parameters from the dynamic query
 	for _, param := range query.Parameters {
+		opts = append(opts, parameterOption(param))
+	}
+
+	return mcp.NewTool(toolName, opts...)
+}
+
+// toolDescription returns the description of a dynamic query tool, followed
+// by its constraints and example calls
+func toolDescription(query *config.DynamicQuery) string {
+	if len(query.Constraints) == 0 && len(query.Examples) == 0 {
+		return query.Description
+	}
+	var builder strings.Builder
+	builder.WriteString(query.Description)
+	section := func(title string) {
+		if builder.Len() > 0 {
+			builder.WriteString("\n\n")
+		}
+		builder.WriteString(title)
+	}
+	if len(query.Constraints) > 0 {
+		section("Constraints:")
+		for _, constraint := range query.Constraints {
+			builder.WriteString("\n- " + constraint.String())
+		}
+	}
+	if len(query.Examples) > 0 {
+		section("Examples:")
+		for _, example := range query.Examples {
+			builder.WriteString("\n- " + example.String())
+		}
+	}
+	return builder.String()
+}
+
+// relativeTimeHint tells agents about relative date-times, see caxiom.ParseDateTime
+const relativeTimeHint = "RFC 3339, or relative to the server clock: now, now-24h, today, yesterday or start of week."
+
+// parameterOption declares a dynamic query parameter with the JSON Schema
+// type and format of its parameter type
+func parameterOption(param config.DynamicParameter) mcp.ToolOption {
+	var propertyOpts []mcp.PropertyOption
+	if param.Required {
+		propertyOpts = append(propertyOpts, mcp.Required())
+	}
+	description := param.Description
+	if param.Example != "" {
+		description = strings.TrimSpace(description + " Example: " + param.Example)
+	}
+	if caxiom.ParamType(param.Type) == caxiom.ParamDateTime {
+		description = strings.TrimSpace(description + " " + relativeTimeHint)
+	}
+	if description != "" {
+		propertyOpts = append(propertyOpts, mcp.Description(description))
+	}
+
+	if !param.Required && param.Default != "" {
+		propertyOpts = append(propertyOpts, parameterDefault(param))
+	}
+
+	paramType := caxiom.ParamType(param.Type)
+	if paramType.IsList() {
+		items := map[string]any{"type": schemaTypeOf(paramType.Elem())}
+		for _, opt := range constraintOptions(param) {
+			opt(items)
+		}
+		propertyOpts = append(propertyOpts, mcp.Items(items), mcp.MaxItems(param.ItemLimit()))
 		if param.Required {
-			opts = append(opts, mcp.WithString(param.Name, mcp.Required()))
-		} else {
-			opts = append(opts, mcp.WithString(param.Name))
+			propertyOpts = append(propertyOpts, mcp.MinItems(1))
 		}
+		return mcp.WithArray(param.Name, propertyOpts...)
 	}
+	propertyOpts = append(propertyOpts, constraintOptions(param)...)
 
-	return mcp.NewTool(toolName, opts...)
+	switch paramType {
+	case caxiom.ParamInteger:
+		return mcp.WithNumber(param.Name, append(propertyOpts, schemaType("integer"))...)
+	case caxiom.ParamNumber:
+		return mcp.WithNumber(param.Name, propertyOpts...)
+	case caxiom.ParamBoolean:
+		return mcp.WithBoolean(param.Name, propertyOpts...)
+	case caxiom.ParamDateTime:
+		return mcp.WithString(param.Name, append(propertyOpts, schemaFormat("date-time"))...)
+	case caxiom.ParamDuration:
+		return mcp.WithString(param.Name, append(propertyOpts, schemaFormat("duration"))...)
+	default:
+		return mcp.WithString(param.Name, propertyOpts...)
+	}
+}
+
+// schemaTypeOf returns the JSON Schema type of a scalar parameter type
+func schemaTypeOf(paramType caxiom.ParamType) string {
+	switch paramType {
+	case caxiom.ParamInteger:
+		return "integer"
+	case caxiom.ParamNumber:
+		return "number"
+	case caxiom.ParamBoolean:
+		return "boolean"
+	default:
+		return "string"
+	}
+}
+
+// parameterDefault advertises the default of an optional parameter as a value
+// of its JSON Schema type
+func parameterDefault(param config.DynamicParameter) mcp.PropertyOption {
+	paramType := caxiom.ParamType(param.Type)
+	if paramType.IsList() {
+		items, err := caxiom.CoerceList(paramType, param.Default, time.Now())
+		values := make([]any, len(items))
+		for i, item := range items {
+			values[i] = schemaValue(paramType.Elem(), item)
+		}
+		return func(schema map[string]any) {
+			if err == nil {
+				schema["default"] = values
+			}
+		}
+	}
+
+	switch paramType {
+	case caxiom.ParamInteger, caxiom.ParamNumber:
+		if value, err := strconv.ParseFloat(param.Default, 64); err == nil {
+			return mcp.DefaultNumber(value)
+		}
+	case caxiom.ParamBoolean:
+		if value, err := strconv.ParseBool(param.Default); err == nil {
+			return mcp.DefaultBool(value)
+		}
+	}
+	return mcp.DefaultString(param.Default)
+}
+
+// constraintOptions advertises the constraints of a parameter in its schema
+func constraintOptions(param config.DynamicParameter) []mcp.PropertyOption {
+	var opts []mcp.PropertyOption
+	if len(param.Enum) > 0 {
+		opts = append(opts, schemaEnum(param))
+	}
+	if param.Pattern != "" {
+		opts = append(opts, mcp.Pattern(param.Pattern))
+	}
+	if param.MaxLength > 0 {
+		opts = append(opts, mcp.MaxLength(param.MaxLength))
+	}
+	if param.Min != nil {
+		opts = append(opts, mcp.Min(*param.Min))
+	}
+	if param.Max != nil {
+		opts = append(opts, mcp.Max(*param.Max))
+	}
+	return opts
+}
+
+// schemaEnum sets the allowed values of a property as values of its JSON
+// Schema type
+func schemaEnum(param config.DynamicParameter) mcp.PropertyOption {
+	values := make([]any, len(param.Enum))
+	for i, value := range param.Enum {
+		values[i] = schemaValue(caxiom.ParamType(param.Type).Elem(), value)
+	}
+	return func(schema map[string]any) {
+		schema["enum"] = values
+	}
+}
+
+// schemaValue converts a canonical value to a value of its JSON Schema type
+func schemaValue(paramType caxiom.ParamType, value string) any {
+	switch paramType {
+	case caxiom.ParamInteger, caxiom.ParamNumber:
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return number
+		}
+	case caxiom.ParamBoolean:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// schemaType overrides the JSON Schema type of a property
+func schemaType(schemaType string) mcp.PropertyOption {
+	return func(schema map[string]any) {
+		schema["type"] = schemaType
+	}
+}
+
+// schemaFormat sets the JSON Schema format of a string property
+func schemaFormat(format string) mcp.PropertyOption {
+	return func(schema map[string]any) {
+		schema["format"] = format
+	}
 }
@@ -0,0 +1,178 @@
+package cserver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom"
+	"github.com/roessland/curated-axiom-mcp/pkg/formatter"
+)
+
+// maxRetainedResults bounds the number of results kept for fetch_more
+const maxRetainedResults = 100
+
+var fetchMoreTool = mcp.NewTool("fetch_more",
+	mcp.WithDescription("Fetch the next page of rows of a truncated query result"),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithOpenWorldHintAnnotation(false),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+	mcp.WithString("continuation_token", mcp.Required(),
+		mcp.Description("Continuation token from a truncated result")),
+)
+
+// PageStore retains truncated query results so later pages can be fetched
+// without running the query again. Continuation tokens expire after a TTL and
+// can only be used from the session that received them.
+type PageStore struct {
+	ttl time.Duration
+
+	mu    sync.Mutex
+	pages map[string]*retainedPage
+
+	now func() time.Time
+}
+
+// retainedPage is the state behind a continuation token
+type retainedPage struct {
+	result    *axiom.QueryResult
+	options   formatter.FormatOptions // Options of the next page
+	session   string
+	expiresAt time.Time
+}
+
+// NewPageStore creates a page store. A zero ttl disables pagination.
+func NewPageStore(ttl time.Duration) *PageStore {
+	return &PageStore{
+		ttl:   ttl,
+		pages: make(map[string]*retainedPage),
+		now:   time.Now,
+	}
+}
+
+// put retains a result and returns the continuation token for its next page
+func (s *PageStore) put(session string, result *axiom.QueryResult, options formatter.FormatOptions) (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate continuation token: %w", err)
+	}
+	token := hex.EncodeToString(b[:])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.removeExpired(now)
+
+	// Make room by dropping the result closest to expiring
+	for len(s.pages) >= maxRetainedResults {
+		oldest := ""
+		for t, page := range s.pages {
+			if oldest == "" || page.expiresAt.Before(s.pages[oldest].expiresAt) {
+				oldest = t
+			}
+		}
+		delete(s.pages, oldest)
+	}
+
+	s.pages[token] = &retainedPage{
+		result:    result,
+		options:   options,
+		session:   session,
+		expiresAt: now.Add(s.ttl),
+	}
+	return token, nil
+}
+
+// get returns the page for a continuation token
+func (s *PageStore) get(session, token string) (*retainedPage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.removeExpired(s.now())
+
+	// Tokens of other sessions are reported as unknown, so they can't be probed
+	page, ok := s.pages[token]
+	if !ok || page.session != session {
+		return nil, fmt.Errorf("continuation token is unknown or has expired, run the query again to get a new one")
+	}
+	return page, nil
+}
+
+func (s *PageStore) removeExpired(now time.Time) {
+	for token, page := range s.pages {
+		if !now.Before(page.expiresAt) {
+			delete(s.pages, token)
+		}
+	}
+}
+
+// sessionID returns the ID of the MCP session the request belongs to, or an
+// empty string if there is no session
+func sessionID(ctx context.Context) string {
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		return session.SessionID()
+	}
+	return ""
+}
+
+// formatPage formats a page of the result as markdown. When rows remain after
+// the page, the result is retained and a continuation token for fetch_more is
+// appended.
+func formatPage(ctx context.Context, pages *PageStore, result *axiom.QueryResult, options formatter.FormatOptions) (string, error) {
+	formatted, err := formatter.NewLLMFormatter().Format(result, options)
+	if err != nil {
+		return "", err
+	}
+	text := formatAsMarkdown(formatted)
+
+	remaining := formatted.Count - options.Offset - options.MaxRows
+	if options.MaxRows <= 0 || remaining <= 0 || pages.ttl <= 0 {
+		return text, nil
+	}
+
+	next := options
+	next.Offset += options.MaxRows
+	token, err := pages.put(sessionID(ctx), result, next)
+	if err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	builder.WriteString(text)
+	builder.WriteString("\n## More Rows\n\n")
+	builder.WriteString(fmt.Sprintf("%d more rows are available. Call fetch_more with continuation_token \"%s\" to get rows %d-%d. The token expires in %s.\n",
+		remaining, token, next.Offset+1, next.Offset+min(remaining, next.MaxRows), pages.ttl))
+	return builder.String(), nil
+}
+
+// FetchMoreHandler returns the next page of a truncated result
+func FetchMoreHandler(pages *PageStore) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Log the tool call request
+		logToolCall("fetch_more", request)
+
+		token, err := request.RequireString("continuation_token")
+		if err != nil {
+			return errorResult(err), nil
+		}
+
+		page, err := pages.get(sessionID(ctx), token)
+		if err != nil {
+			return errorResult(err), nil
+		}
+
+		textResponse, err := formatPage(ctx, pages, page.result, page.options)
+		if err != nil {
+			return failedResult("failed to format results"), nil
+		}
+		return successResult(textResponse), nil
+	}
+}
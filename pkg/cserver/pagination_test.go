@@ -0,0 +1,85 @@
+package cserver
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom"
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom/axiomtest"
+	"github.com/roessland/curated-axiom-mcp/pkg/formatter"
+)
+
+var continuationTokenRegex = regexp.MustCompile(`continuation_token "([0-9a-f]+)"`)
+
+func TestFetchMore(t *testing.T) {
+	manager, srv := newTestManager(t)
+	rows := make([][]any, 250)
+	for i := range rows {
+		rows[i] = []any{i}
+	}
+	srv.SetResultContaining("['numbers']", axiomtest.Result{
+		Fields: []query.Field{{Name: "n", Type: "integer"}},
+		Rows:   rows,
+	})
+
+	first := callTool(t, manager, "run_query", map[string]any{"apl": "['numbers']"})
+	match := continuationTokenRegex.FindStringSubmatch(first)
+	if match == nil || !strings.Contains(first, "150 more rows are available") {
+		t.Fatalf("Expected a continuation token, got:\n%s", first)
+	}
+
+	second := callTool(t, manager, "fetch_more", map[string]any{"continuation_token": match[1]})
+	if !strings.Contains(second, "Showing rows 101-200") || !strings.Contains(second, "\n100\n") {
+		t.Errorf("Expected rows 101-200, got:\n%s", second)
+	}
+	match = continuationTokenRegex.FindStringSubmatch(second)
+	if match == nil {
+		t.Fatalf("Expected a continuation token for the last page, got:\n%s", second)
+	}
+
+	third := callTool(t, manager, "fetch_more", map[string]any{"continuation_token": match[1]})
+	if !strings.Contains(third, "Showing rows 201-250") || continuationTokenRegex.MatchString(third) {
+		t.Errorf("Expected last page without continuation token, got:\n%s", third)
+	}
+
+	if srv.QueryCount() != 1 {
+		t.Errorf("Expected pages to be served without querying again, got %d queries", srv.QueryCount())
+	}
+}
+
+func TestFetchMoreUnknownToken(t *testing.T) {
+	manager, _ := newTestManager(t)
+
+	text := callTool(t, manager, "fetch_more", map[string]any{"continuation_token": "deadbeef"})
+
+	if !strings.Contains(text, "unknown or has expired") {
+		t.Errorf("Expected unknown token error, got:\n%s", text)
+	}
+}
+
+func TestPageStoreSessionAndExpiry(t *testing.T) {
+	pages := NewPageStore(time.Minute)
+	now := time.Now()
+	pages.now = func() time.Time { return now }
+
+	token, err := pages.put("session-a", &axiom.QueryResult{}, formatter.FormatOptions{Offset: 100})
+	if err != nil {
+		t.Fatalf("Failed to retain page: %v", err)
+	}
+
+	if _, err := pages.get("session-b", token); err == nil {
+		t.Error("Expected token to be rejected in another session")
+	}
+	page, err := pages.get("session-a", token)
+	if err != nil || page.options.Offset != 100 {
+		t.Errorf("Expected page at offset 100, got %+v, %v", page, err)
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := pages.get("session-a", token); err == nil {
+		t.Error("Expected token to expire")
+	}
+}
@@ -0,0 +1,92 @@
+package cserver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom"
+	"github.com/roessland/curated-axiom-mcp/pkg/config"
+)
+
+// Profile is an Axiom organization the server can query
+type Profile struct {
+	Name   string
+	Client *axiom.Client      // Shared by all tool handlers
+	Cache  *axiom.ResultCache // Results of curated tool calls and dataset discovery
+}
+
+// Profiles holds the configured profiles in config order. The first profile
+// is used when a tool call doesn't name one.
+type Profiles struct {
+	list   []*Profile
+	byName map[string]*Profile
+}
+
+// NewProfiles creates one long-lived client and result cache per profile, so
+// connections are reused and the concurrency limit applies across all tool
+// calls
+func NewProfiles(appConfig *config.AppConfig) *Profiles {
+	p := &Profiles{byName: make(map[string]*Profile)}
+	for _, profileConfig := range appConfig.AxiomProfiles() {
+		clientConfig := profileConfig.ClientConfig()
+		clientConfig.MaxConcurrentQueries = appConfig.Queries.MaxConcurrent
+
+		profile := &Profile{
+			Name:   profileConfig.Name,
+			Client: axiom.NewClient(clientConfig),
+			Cache: axiom.NewResultCache(appConfig.Queries.ResultCacheTTL,
+				appConfig.Queries.ResultCacheRelativeTTL, appConfig.Queries.ResultCacheMaxMB*1024*1024),
+		}
+		p.list = append(p.list, profile)
+		p.byName[profile.Name] = profile
+	}
+	return p
+}
+
+// Default returns the first configured profile
+func (p *Profiles) Default() *Profile {
+	return p.list[0]
+}
+
+// Get returns the profile with the given name, or the default profile if
+// name is empty
+func (p *Profiles) Get(name string) (*Profile, error) {
+	if name == "" {
+		return p.Default(), nil
+	}
+	profile, ok := p.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q, available profiles: %s", name, strings.Join(p.Names(), ", "))
+	}
+	return profile, nil
+}
+
+// Names returns the profile names in config order
+func (p *Profiles) Names() []string {
+	names := make([]string, len(p.list))
+	for i, profile := range p.list {
+		names[i] = profile.Name
+	}
+	return names
+}
+
+// FromRequest returns the profile named by the optional profile argument
+func (p *Profiles) FromRequest(request mcp.CallToolRequest) (*Profile, error) {
+	return p.Get(request.GetString("profile", ""))
+}
+
+// withProfileArgument adds the optional profile argument to a tool
+func (p *Profiles) withProfileArgument(tool mcp.Tool) mcp.Tool {
+	properties := make(map[string]any, len(tool.InputSchema.Properties)+1)
+	for name, property := range tool.InputSchema.Properties {
+		properties[name] = property
+	}
+	properties["profile"] = map[string]any{
+		"type":        "string",
+		"description": fmt.Sprintf("Axiom profile to use, defaults to %s", p.Default().Name),
+		"enum":        p.Names(),
+	}
+	tool.InputSchema.Properties = properties
+	return tool
+}
@@ -0,0 +1,84 @@
+package cserver
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom/axiomtest"
+	"github.com/roessland/curated-axiom-mcp/pkg/config"
+)
+
+// newProfilesTestManager creates an MCP manager with a prod and a staging
+// profile, each backed by its own fake Axiom server
+func newProfilesTestManager(t *testing.T) (*MCPManager, *axiomtest.Server, *axiomtest.Server) {
+	t.Helper()
+
+	// Debug logs are written to the home directory
+	t.Setenv("HOME", t.TempDir())
+
+	prod := axiomtest.NewServer(t)
+	staging := axiomtest.NewServer(t)
+	for _, srv := range []*axiomtest.Server{prod, staging} {
+		if err := srv.LoadFixtures(axiomtest.DefaultFixtures()); err != nil {
+			t.Fatalf("Failed to load fixtures: %v", err)
+		}
+	}
+
+	appConfig := &config.AppConfig{
+		Profiles: []config.AxiomConfig{
+			{Name: "prod", Token: axiomtest.Token, URL: prod.URL},
+			{Name: "staging", Token: axiomtest.Token, URL: staging.URL},
+		},
+		Queries: config.QueriesConfig{
+			CacheTTL: 5 * time.Minute,
+			Timeout:  5 * time.Second,
+
+			ResultCacheTTL:         5 * time.Minute,
+			ResultCacheRelativeTTL: 30 * time.Second,
+			ResultCacheMaxMB:       1,
+
+			PageTTL: 10 * time.Minute,
+		},
+	}
+	registry := config.NewRegistryWithProfiles(appConfig.AxiomProfiles(), appConfig.Queries.CacheTTL)
+
+	manager := NewMCP(appConfig, registry)
+	if err := manager.LoadDynamicTools(); err != nil {
+		t.Fatalf("Failed to load dynamic tools: %v", err)
+	}
+
+	return manager, prod, staging
+}
+
+func TestProfilesDynamicTools(t *testing.T) {
+	manager, prod, staging := newProfilesTestManager(t)
+
+	text := callTool(t, manager, "staging_entity_data", entityDataArgs)
+
+	if !strings.Contains(text, "Found 4 records") {
+		t.Errorf("Expected results, got:\n%s", text)
+	}
+	if prod.QueryCount() != 0 || staging.QueryCount() != 1 {
+		t.Errorf("Expected query to run in staging only, got prod=%d staging=%d", prod.QueryCount(), staging.QueryCount())
+	}
+}
+
+func TestProfilesRunQuery(t *testing.T) {
+	manager, prod, staging := newProfilesTestManager(t)
+
+	callTool(t, manager, "run_query", map[string]any{"apl": "['events'] | limit 10"})
+	if prod.QueryCount() != 1 {
+		t.Errorf("Expected query without profile to run in the default profile, got %d", prod.QueryCount())
+	}
+
+	callTool(t, manager, "run_query", map[string]any{"apl": "['events'] | limit 10", "profile": "staging"})
+	if staging.QueryCount() != 1 {
+		t.Errorf("Expected query to run in staging, got %d", staging.QueryCount())
+	}
+
+	text := callTool(t, manager, "run_query", map[string]any{"apl": "['events'] | limit 10", "profile": "eu"})
+	if !strings.Contains(text, `unknown profile "eu", available profiles: prod, staging`) {
+		t.Errorf("Expected unknown profile error, got:\n%s", text)
+	}
+}
@@ -0,0 +1,145 @@
+package cserver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom"
+	"github.com/roessland/curated-axiom-mcp/pkg/config"
+	"github.com/roessland/curated-axiom-mcp/pkg/formatter"
+	"github.com/roessland/curated-axiom-mcp/pkg/utils"
+)
+
+// datasetsCacheKey is the result cache key of the dataset list. It is not
+// valid APL, so it cannot collide with cached query results.
+const datasetsCacheKey = "#list_datasets"
+
+var listDatasetsTool = mcp.NewTool("list_datasets",
+	mcp.WithDescription("List the Axiom datasets exposed for discovery. run_query is not limited to these datasets"),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithOpenWorldHintAnnotation(false),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+var describeDatasetTool = mcp.NewTool("describe_dataset",
+	mcp.WithDescription("Describe the fields of an Axiom dataset: name, type and example values from recent events"),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithOpenWorldHintAnnotation(false),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+	mcp.WithString("dataset", mcp.Required(), mcp.Description("Name of the dataset, as returned by list_datasets")),
+)
+
+// ListDatasetsHandler lists the datasets visible to the token and allowed by
+// the datasets config
+func ListDatasetsHandler(profiles *Profiles, appConfig *config.AppConfig) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Log the tool call request
+		logToolCall("list_datasets", request)
+
+		profile, err := profiles.FromRequest(request)
+		if err != nil {
+			return errorResult(err), nil
+		}
+
+		formatOptions := formatter.FormatOptions{
+			Format:      "table",
+			LLMFriendly: true,
+			MaxRows:     100,
+		}
+
+		var result *axiom.QueryResult
+		if cached, ok := profile.Cache.Get(datasetsCacheKey); ok {
+			result = cached.Result
+			formatOptions.CachedAt = cached.FetchedAt
+		} else {
+			timeout := queryTimeout(appConfig, 0)
+			queryCtx, cancel := withQueryTimeout(ctx, timeout)
+			defer cancel()
+
+			datasets, err := profile.Client.Datasets(queryCtx)
+			if err != nil {
+				slog.Error("Failed to list datasets", "profile", profile.Name, "error", err)
+				return failedResult(formatAxiomError("failed to list datasets", utils.ClassifyAxiomError(err))), nil
+			}
+
+			// Only expose allowed datasets
+			allowed := make([]axiom.Dataset, 0, len(datasets))
+			for _, dataset := range datasets {
+				if appConfig.Datasets.Allowed(dataset.Name) {
+					allowed = append(allowed, dataset)
+				}
+			}
+			if len(allowed) == 0 {
+				return successResult("No datasets available."), nil
+			}
+
+			result = axiom.DatasetsResult(allowed)
+			profile.Cache.Put(datasetsCacheKey, result)
+		}
+
+		formatted, err := formatter.NewLLMFormatter().Format(result, formatOptions)
+		if err != nil {
+			return failedResult("failed to format results"), nil
+		}
+
+		return successResult(formatAsMarkdown(formatted)), nil
+	}
+}
+
+// DescribeDatasetHandler describes the fields of a dataset by sampling its
+// most recent events
+func DescribeDatasetHandler(profiles *Profiles, appConfig *config.AppConfig) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Log the tool call request
+		logToolCall("describe_dataset", request)
+
+		dataset, err := request.RequireString("dataset")
+		if err != nil {
+			return errorResult(err), nil
+		}
+		profile, err := profiles.FromRequest(request)
+		if err != nil {
+			return errorResult(err), nil
+		}
+		if !appConfig.Datasets.Allowed(dataset) {
+			return failedResult(fmt.Sprintf("dataset %q is not exposed by this server, use list_datasets to see the available datasets", dataset)), nil
+		}
+
+		apl := axiom.DescribeDatasetAPL(dataset)
+		formatOptions := formatter.FormatOptions{
+			Format:      "table",
+			LLMFriendly: true,
+			MaxRows:     100,
+			APLQuery:    apl,
+		}
+
+		var result *axiom.QueryResult
+		if cached, ok := profile.Cache.Get(apl); ok {
+			result = cached.Result
+			formatOptions.CachedAt = cached.FetchedAt
+		} else {
+			timeout := queryTimeout(appConfig, 0)
+			queryCtx, cancel := withQueryTimeout(ctx, timeout)
+			defer cancel()
+
+			result, err = profile.Client.DescribeDataset(queryCtx, dataset)
+			if err != nil {
+				slog.Error("Failed to describe dataset", "profile", profile.Name, "dataset", dataset, "error", err)
+				return queryErrorResult(ctx, queryCtx, err, timeout), nil
+			}
+			profile.Cache.Put(apl, result)
+		}
+
+		formatted, err := formatter.NewLLMFormatter().Format(result, formatOptions)
+		if err != nil {
+			return failedResult("failed to format results"), nil
+		}
+
+		return successResult(formatAsMarkdown(formatted)), nil
+	}
+}
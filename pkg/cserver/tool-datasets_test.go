@@ -0,0 +1,69 @@
+package cserver
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestListDatasetsHandler(t *testing.T) {
+	manager, srv := newTestManager(t)
+
+	first := callTool(t, manager, "list_datasets", nil)
+	for _, want := range []string{"Found 2 records", "events,User activity events", "audit-log"} {
+		if !strings.Contains(first, want) {
+			t.Errorf("Expected %q in result, got:\n%s", want, first)
+		}
+	}
+
+	second := callTool(t, manager, "list_datasets", nil)
+	if !strings.Contains(second, "Served from cache") {
+		t.Errorf("Expected second call to be served from cache, got:\n%s", second)
+	}
+	if got := len(srv.Requests()); got != 2 {
+		// One request for starred queries, one for datasets
+		t.Errorf("Expected 2 requests, got %d", got)
+	}
+}
+
+func TestListDatasetsHandlerAllowlist(t *testing.T) {
+	manager, _ := newTestManager(t)
+	manager.appConfig.Datasets.Allow = []string{"audit-*"}
+
+	text := callTool(t, manager, "list_datasets", nil)
+
+	if !strings.Contains(text, "audit-log") {
+		t.Errorf("Expected allowed dataset in result, got:\n%s", text)
+	}
+	if strings.Contains(text, "User activity events") {
+		t.Errorf("Expected events dataset to be hidden, got:\n%s", text)
+	}
+}
+
+func TestDescribeDatasetHandler(t *testing.T) {
+	manager, srv := newTestManager(t)
+
+	text := callTool(t, manager, "describe_dataset", map[string]any{"dataset": "events"})
+
+	for _, want := range []string{"### duration_ms", "- **Type**: integer", "### event", "upload"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in result, got:\n%s", want, text)
+		}
+	}
+	if srv.QueryCount() != 1 {
+		t.Errorf("Expected 1 query, got %d", srv.QueryCount())
+	}
+}
+
+func TestDescribeDatasetHandlerNotAllowed(t *testing.T) {
+	manager, srv := newTestManager(t)
+	manager.appConfig.Datasets.Allow = []string{"audit-*"}
+
+	text := callTool(t, manager, "describe_dataset", map[string]any{"dataset": "events"})
+
+	if !strings.Contains(text, "not exposed") {
+		t.Errorf("Expected dataset to be rejected, got:\n%s", text)
+	}
+	if srv.QueryCount() != 0 {
+		t.Errorf("Expected no query to be executed, got %d", srv.QueryCount())
+	}
+}
@@ -2,8 +2,10 @@ package cserver
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -11,10 +13,11 @@ import (
 	"github.com/roessland/curated-axiom-mcp/pkg/caxiom"
 	"github.com/roessland/curated-axiom-mcp/pkg/config"
 	"github.com/roessland/curated-axiom-mcp/pkg/formatter"
+	"github.com/roessland/curated-axiom-mcp/pkg/utils"
 )
 
 // CreateDynamicQueryHandler creates a handler for a dynamic query tool
-func CreateDynamicQueryHandler(toolName string, registry *config.Registry, appConfig *config.AppConfig) server.ToolHandlerFunc {
+func CreateDynamicQueryHandler(toolName string, registry *config.Registry, profile *Profile, pages *PageStore, appConfig *config.AppConfig) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// Log the tool call request
 		logToolCall(toolName, request)
@@ -25,60 +28,170 @@ func CreateDynamicQueryHandler(toolName string, registry *config.Registry, appCo
 			return errorResult(fmt.Errorf("query not found: %w", err)), nil
 		}
 
-		// Extract parameters from the request
-		params := make(map[string]interface{})
-		for _, param := range query.Parameters {
-			value, err := request.RequireString(param.Name)
-			if err != nil {
-				if param.Required {
-					return errorResult(fmt.Errorf("missing required parameter %s: %w", param.Name, err)), nil
-				}
-				// Use empty string for optional missing parameters
-				params[param.Name] = ""
-			} else {
-				params[param.Name] = value
-			}
-		}
-
-		// Render the template with provided parameters
-		templateExecutor := caxiom.NewTemplateExecutor()
-		renderedAPL, err := templateExecutor.RenderTemplate(query.TemplateAPL, params)
+		// Validate the arguments and render the template with them
+		renderedAPL, params, err := renderDynamicQuery(query, request, time.Now())
 		if err != nil {
-			return errorResult(fmt.Errorf("failed to render query template: %w", err)), nil
+			return errorResult(err), nil
 		}
 		
 		// Debug: log the rendered APL
 		slog.Debug("Rendered APL query", "tool_name", toolName, "rendered_apl", renderedAPL)
 
-		// Create Axiom client and execute the query
-		clientConfig := &axiom.AxiomConfig{
-			Token:   appConfig.Axiom.Token,
-			OrgID:   appConfig.Axiom.OrgID,
-			Dataset: appConfig.Axiom.Dataset,
-			URL:     appConfig.Axiom.URL,
-		}
-		client := axiom.NewClient(clientConfig)
-		result, err := client.ExecuteQuery(renderedAPL)
-		if err != nil {
-			return failedResult(fmt.Sprintf("query execution failed: %v", err)), nil
-		}
-
 		// Format result for LLM
-		llmFormatter := formatter.NewLLMFormatter()
 		formatOptions := formatter.FormatOptions{
 			Format:      "table",
 			LLMFriendly: true,
 			MaxRows:     100,
 			APLQuery:    renderedAPL, // Pass the rendered APL query
+
+			ResolvedTimes: resolvedTimes(query, request, params),
 		}
 
-		formatted, err := llmFormatter.Format(result, formatOptions)
+		// Serve from cache or execute the query
+		var result *axiom.QueryResult
+		if cached, ok := profile.Cache.Get(renderedAPL); ok {
+			slog.Debug("Serving query result from cache", "tool_name", toolName, "fetched_at", cached.FetchedAt)
+			result = cached.Result
+			formatOptions.CachedAt = cached.FetchedAt
+		} else {
+			timeout := queryTimeout(appConfig, query.Timeout)
+			queryCtx, cancel := withQueryTimeout(ctx, timeout)
+			defer cancel()
+
+			result, err = profile.Client.ExecuteQuery(queryCtx, renderedAPL)
+			if err != nil {
+				slog.Error("Query execution failed", "tool_name", toolName, "error", err)
+				return queryErrorResult(ctx, queryCtx, err, timeout), nil
+			}
+			profile.Cache.Put(renderedAPL, result)
+		}
+
+		// Format as markdown/plaintext response
+		textResponse, err := formatPage(ctx, pages, result, formatOptions)
 		if err != nil {
 			return failedResult("failed to format results"), nil
 		}
 
-		// Format as markdown/plaintext response
-		textResponse := formatAsMarkdown(formatted)
 		return successResult(textResponse), nil
 	}
-}
\ No newline at end of file
+}
+
+// renderDynamicQuery validates the arguments of a dynamic query call against
+// the parameters and constraints, and renders the template with them. It
+// returns the rendered APL and the coerced parameters.
+func renderDynamicQuery(query *config.DynamicQuery, request mcp.CallToolRequest, now time.Time) (string, map[string]interface{}, error) {
+	params, err := coerceArguments(query, request, now)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := caxiom.CheckConstraints(query.Constraints, params, now); err != nil {
+		return "", nil, err
+	}
+
+	templateExecutor := caxiom.NewTemplateExecutor()
+	templateExecutor.Supplied = suppliedArguments(request)
+	renderedAPL, err := templateExecutor.RenderQuery(query.TemplateAPL, params, parameterTypes(query))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to render query template: %w", err)
+	}
+	return renderedAPL, params, nil
+}
+
+// coerceArguments validates the arguments of a dynamic query against the
+// parameter types and constraints and returns the values to render into the template. All
+// invalid arguments are reported at once, so the agent can fix them together.
+// Relative date-times are resolved against now.
+func coerceArguments(query *config.DynamicQuery, request mcp.CallToolRequest, now time.Time) (map[string]interface{}, error) {
+	args := request.GetArguments()
+	params := make(map[string]interface{})
+	var errs []error
+	for _, param := range query.Parameters {
+		value, ok := args[param.Name]
+		if !ok || value == nil {
+			if param.Required {
+				errs = append(errs, utils.NewParameterError(param.Name, "is required"))
+				continue
+			}
+			if param.Default == "" {
+				// Only used in optional sections
+				continue
+			}
+			value = param.Default
+		}
+
+		if paramType := caxiom.ParamType(param.Type); paramType.IsList() {
+			items, err := caxiom.CoerceList(paramType, value, now)
+			if err == nil {
+				err = param.CheckList(items)
+			}
+			if err == nil && param.Required && len(items) == 0 {
+				err = fmt.Errorf("must have at least one item")
+			}
+			if err != nil {
+				errs = append(errs, utils.NewParameterError(param.Name, err.Error()))
+				continue
+			}
+			params[param.Name] = items
+			continue
+		}
+
+		coerced, err := caxiom.CoerceArgument(caxiom.ParamType(param.Type), value, now)
+		if err == nil {
+			err = param.Check(coerced)
+		}
+		if err != nil {
+			errs = append(errs, utils.NewParameterError(param.Name, err.Error()))
+			continue
+		}
+		params[param.Name] = coerced
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid arguments:\n%w", errors.Join(errs...))
+	}
+	return params, nil
+}
+
+// suppliedArguments returns the names of the arguments given in a tool call,
+// for optional sections of the template
+func suppliedArguments(request mcp.CallToolRequest) map[string]bool {
+	supplied := make(map[string]bool)
+	for name, value := range request.GetArguments() {
+		if value != nil {
+			supplied[name] = true
+		}
+	}
+	return supplied
+}
+
+// resolvedTimes returns the date-time arguments of a dynamic query as given
+// and as resolved, to echo them in the response
+func resolvedTimes(query *config.DynamicQuery, request mcp.CallToolRequest, params map[string]interface{}) []formatter.ResolvedTime {
+	args := request.GetArguments()
+	var times []formatter.ResolvedTime
+	for _, param := range query.Parameters {
+		if caxiom.ParamType(param.Type) != caxiom.ParamDateTime {
+			continue
+		}
+		value, _ := params[param.Name].(string)
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			continue
+		}
+		input, ok := args[param.Name].(string)
+		if !ok {
+			input = param.Default
+		}
+		times = append(times, formatter.ResolvedTime{Name: param.Name, Input: input, Time: t})
+	}
+	return times
+}
+
+// parameterTypes returns the type of every parameter of a dynamic query
+func parameterTypes(query *config.DynamicQuery) map[string]caxiom.ParamType {
+	types := make(map[string]caxiom.ParamType, len(query.Parameters))
+	for _, param := range query.Parameters {
+		types[param.Name] = caxiom.ParamType(param.Type)
+	}
+	return types
+}
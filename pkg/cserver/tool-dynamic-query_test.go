@@ -0,0 +1,591 @@
+package cserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom"
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom/axiomtest"
+	"github.com/roessland/curated-axiom-mcp/pkg/caxiom"
+	"github.com/roessland/curated-axiom-mcp/pkg/config"
+)
+
+// newTestManager creates an MCP manager backed by a fake Axiom server
+func newTestManager(t *testing.T) (*MCPManager, *axiomtest.Server) {
+	t.Helper()
+
+	// Debug logs are written to the home directory
+	t.Setenv("HOME", t.TempDir())
+
+	srv := axiomtest.NewServer(t)
+	if err := srv.LoadFixtures(axiomtest.DefaultFixtures()); err != nil {
+		t.Fatalf("Failed to load fixtures: %v", err)
+	}
+
+	appConfig := &config.AppConfig{
+		Axiom: config.AxiomConfig{
+			Token: axiomtest.Token,
+			URL:   srv.URL,
+		},
+		Queries: config.QueriesConfig{
+			CacheTTL: 5 * time.Minute,
+			Timeout:  5 * time.Second,
+
+			ResultCacheTTL:         5 * time.Minute,
+			ResultCacheRelativeTTL: 30 * time.Second,
+			ResultCacheMaxMB:       1,
+
+			PageTTL: 10 * time.Minute,
+		},
+	}
+	registry := config.NewRegistryWithAxiom(&appConfig.Axiom, appConfig.Queries.CacheTTL)
+
+	manager := NewMCP(appConfig, registry)
+	if err := manager.LoadDynamicTools(); err != nil {
+		t.Fatalf("Failed to load dynamic tools: %v", err)
+	}
+
+	return manager, srv
+}
+
+// callTool calls a tool through the MCP server's JSON-RPC message handler
+func callTool(t *testing.T, manager *MCPManager, name string, args map[string]any) string {
+	t.Helper()
+	return callToolContext(t, context.Background(), manager, name, args)
+}
+
+// callToolContext calls a tool like callTool, with ctx as the request context
+func callToolContext(t *testing.T, ctx context.Context, manager *MCPManager, name string, args map[string]any) string {
+	t.Helper()
+
+	message, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "tools/call",
+		"params": map[string]any{
+			"name":      name,
+			"arguments": args,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal request: %v", err)
+	}
+
+	response := manager.GetServer().HandleMessage(ctx, message)
+	rpcResponse, ok := response.(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatalf("Tool %s returned error: %+v", name, response)
+	}
+	result, ok := rpcResponse.Result.(mcp.CallToolResult)
+	if !ok {
+		t.Fatalf("Unexpected result type %T", rpcResponse.Result)
+	}
+	return result.Content[0].(mcp.TextContent).Text
+}
+
+var entityDataArgs = map[string]any{
+	"EntityId":  "example-entity-id",
+	"StartTime": "2025-06-25T00:00:00Z",
+	"EndTime":   "2025-06-26T00:00:00Z",
+}
+
+func TestDynamicQueryHandler(t *testing.T) {
+	manager, srv := newTestManager(t)
+
+	text := callTool(t, manager, "entity_data", entityDataArgs)
+
+	if !strings.Contains(text, "Found 4 records") {
+		t.Errorf("Expected summary with 4 records, got:\n%s", text)
+	}
+	if !strings.Contains(text, "## Results") {
+		t.Errorf("Expected results section, got:\n%s", text)
+	}
+
+	reqs := srv.Requests()
+	apl := reqs[len(reqs)-1].APL
+	if !strings.Contains(apl, "q_entity_id:string = 'example-entity-id'") {
+		t.Errorf("Expected rendered entity id in APL, got:\n%s", apl)
+	}
+	if !strings.Contains(apl, "datetime(2025-06-25T00:00:00Z)") {
+		t.Errorf("Expected rendered start time in APL, got:\n%s", apl)
+	}
+}
+
+func TestDynamicQueryHandlerCachesResults(t *testing.T) {
+	manager, srv := newTestManager(t)
+
+	first := callTool(t, manager, "entity_data", entityDataArgs)
+	second := callTool(t, manager, "entity_data", entityDataArgs)
+
+	if srv.QueryCount() != 1 {
+		t.Errorf("Expected second call to be served from cache, got %d queries", srv.QueryCount())
+	}
+	if strings.Contains(first, "Served from cache") {
+		t.Errorf("Expected first call to be fresh, got:\n%s", first)
+	}
+	if !strings.Contains(second, "Served from cache: data is 0 s old") {
+		t.Errorf("Expected second call to mention the cache, got:\n%s", second)
+	}
+}
+
+func TestDynamicQueryHandlerMissingParameter(t *testing.T) {
+	manager, srv := newTestManager(t)
+
+	text := callTool(t, manager, "entity_data", map[string]any{"EntityId": "example-entity-id"})
+
+	if !strings.Contains(text, "parameter 'StartTime': is required") || !strings.Contains(text, "parameter 'EndTime': is required") {
+		t.Errorf("Expected missing parameter errors, got:\n%s", text)
+	}
+	if srv.QueryCount() != 0 {
+		t.Errorf("Expected no query to be executed, got %d", srv.QueryCount())
+	}
+}
+
+func TestDynamicQueryHandlerInvalidArgument(t *testing.T) {
+	manager, srv := newTestManager(t)
+
+	text := callTool(t, manager, "entity_data", map[string]any{
+		"EntityId":  "example-entity-id",
+		"StartTime": "yesterday at noon",
+		"EndTime":   "2025-06-26T02:00:00+02:00",
+	})
+
+	if !strings.Contains(text, "parameter 'StartTime': must be an RFC 3339 date-time") {
+		t.Errorf("Expected date-time error, got:\n%s", text)
+	}
+	if strings.Contains(text, "EndTime") {
+		t.Errorf("Expected EndTime with offset to be accepted, got:\n%s", text)
+	}
+	if srv.QueryCount() != 0 {
+		t.Errorf("Expected no query to be executed, got %d", srv.QueryCount())
+	}
+}
+
+func TestDynamicQueryHandlerConstraints(t *testing.T) {
+	manager, srv := newTestManager(t)
+
+	text := callTool(t, manager, "entity_data", map[string]any{
+		"EntityId":  "example-entity-id",
+		"StartTime": "2025-06-20T00:00:00Z",
+		"EndTime":   "2025-06-26T00:00:00Z",
+	})
+
+	if !strings.Contains(text, "EndTime must be at most 24h after StartTime, got 6d") {
+		t.Errorf("Expected max window error, got:\n%s", text)
+	}
+	if !strings.Contains(text, "Nearest valid window: StartTime=2025-06-25T00:00:00Z, EndTime=2025-06-26T00:00:00Z") {
+		t.Errorf("Expected nearest valid window, got:\n%s", text)
+	}
+	if srv.QueryCount() != 0 {
+		t.Errorf("Expected no query to be executed, got %d", srv.QueryCount())
+	}
+}
+
+func TestCreateDynamicToolDescription(t *testing.T) {
+	tool := createDynamicTool("constrained", &config.DynamicQuery{
+		Description: "Get events for a single entity",
+		Constraints: []caxiom.Constraint{
+			{Text: "Prefer short windows"},
+			{Kind: caxiom.ConstraintMaxWindow, Start: "StartTime", End: "EndTime", Max: "24h"},
+		},
+	})
+
+	want := "Get events for a single entity\n\nConstraints:\n- Prefer short windows\n- EndTime must be at most 24h after StartTime"
+	if tool.Description != want {
+		t.Errorf("Expected description %q, got %q", want, tool.Description)
+	}
+
+	tool = createDynamicTool("examples", &config.DynamicQuery{
+		Examples: []caxiom.Example{
+			{Params: map[string]any{"StartTime": "now-24h", "EntityId": "abc"}, Description: "one day of events"},
+			{},
+		},
+	})
+	want = "Examples:\n- {\"EntityId\":\"abc\",\"StartTime\":\"now-24h\"} (one day of events)\n- {}"
+	if tool.Description != want {
+		t.Errorf("Expected description %q, got %q", want, tool.Description)
+	}
+}
+
+func TestDynamicQueryHandlerNormalizesDateTime(t *testing.T) {
+	manager, srv := newTestManager(t)
+
+	args := map[string]any{
+		"EntityId":  "example-entity-id",
+		"StartTime": "2025-06-25T02:00:00+02:00",
+		"EndTime":   "2025-06-26T00:00:00Z",
+	}
+	callTool(t, manager, "entity_data", args)
+
+	reqs := srv.Requests()
+	if apl := reqs[len(reqs)-1].APL; !strings.Contains(apl, "datetime(2025-06-25T00:00:00Z)") {
+		t.Errorf("Expected start time converted to UTC, got:\n%s", apl)
+	}
+}
+
+func TestDynamicQueryHandlerRelativeTimes(t *testing.T) {
+	manager, srv := newTestManager(t)
+
+	text := callTool(t, manager, "entity_data", map[string]any{
+		"EntityId":  "example-entity-id",
+		"StartTime": "now-24h",
+		"EndTime":   "now",
+	})
+
+	reqs := srv.Requests()
+	if len(reqs) == 0 {
+		t.Fatalf("Expected a query to be executed, got:\n%s", text)
+	}
+	match := regexp.MustCompile(`Date-time arguments in UTC: StartTime=(\S+) \(from "now-24h"\), EndTime=(\S+) \(from "now"\)\.`).FindStringSubmatch(text)
+	if match == nil {
+		t.Fatalf("Expected resolved times in the response, got:\n%s", text)
+	}
+	start, err := time.Parse(time.RFC3339, match[1])
+	if err != nil || time.Since(start) < 24*time.Hour || time.Since(start) > 24*time.Hour+time.Minute {
+		t.Errorf("Expected StartTime 24 hours ago, got %s", match[1])
+	}
+	if apl := reqs[len(reqs)-1].APL; !strings.Contains(apl, "datetime("+match[1]+")") || !strings.Contains(apl, "datetime("+match[2]+")") {
+		t.Errorf("Expected resolved times in APL, got:\n%s", apl)
+	}
+}
+
+func TestDynamicQueryHandlerCachesRelativeTimes(t *testing.T) {
+	manager, srv := newTestManager(t)
+	args := map[string]any{
+		"EntityId":  "example-entity-id",
+		"StartTime": "now-24h",
+		"EndTime":   "now",
+	}
+
+	// Relative times are truncated to the minute, so don't straddle one
+	if now := time.Now(); now.Second() == 59 {
+		time.Sleep(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+	}
+	callTool(t, manager, "entity_data", args)
+	second := callTool(t, manager, "entity_data", args)
+
+	if srv.QueryCount() != 1 {
+		t.Errorf("Expected second call to be served from cache, got %d queries", srv.QueryCount())
+	}
+	if !strings.Contains(second, "Served from cache") {
+		t.Errorf("Expected second call to mention the cache, got:\n%s", second)
+	}
+}
+
+func TestDynamicQueryHandlerEscapesStrings(t *testing.T) {
+	manager, srv := newTestManager(t)
+
+	args := map[string]any{
+		"EntityId":  "x' or 1==1 or id=='",
+		"StartTime": "2025-06-25T00:00:00Z",
+		"EndTime":   "2025-06-26T00:00:00Z",
+	}
+	callTool(t, manager, "entity_data", args)
+
+	reqs := srv.Requests()
+	if apl := reqs[len(reqs)-1].APL; !strings.Contains(apl, `q_entity_id:string = 'x\' or 1==1 or id==\''`) {
+		t.Errorf("Expected entity id to be escaped, got:\n%s", apl)
+	}
+}
+
+func TestCreateDynamicToolSchema(t *testing.T) {
+	tool := createDynamicTool("typed", &config.DynamicQuery{
+		Parameters: []config.DynamicParameter{
+			{Name: "Limit", Type: "integer", Required: true},
+			{Name: "Ratio", Type: "number", Required: true},
+			{Name: "Verbose", Type: "boolean", Default: "false"},
+			{Name: "Start", Type: "date-time", Required: true, Description: "Start of interval.", Example: "2025-06-25T00:00:00Z"},
+			{Name: "Window", Type: "duration", Required: true},
+			{Name: "Id", Type: "string", Required: true},
+		},
+	})
+
+	want := map[string][2]string{
+		"Limit":   {"integer", ""},
+		"Ratio":   {"number", ""},
+		"Verbose": {"boolean", ""},
+		"Start":   {"string", "date-time"},
+		"Window":  {"string", "duration"},
+		"Id":      {"string", ""},
+	}
+	for name, w := range want {
+		schema, ok := tool.InputSchema.Properties[name].(map[string]any)
+		if !ok {
+			t.Fatalf("Expected property %s, got %+v", name, tool.InputSchema.Properties)
+		}
+		format, _ := schema["format"].(string)
+		if schema["type"] != w[0] || format != w[1] {
+			t.Errorf("Expected %s to have type %s and format %q, got %+v", name, w[0], w[1], schema)
+		}
+	}
+	if got := tool.InputSchema.Properties["Start"].(map[string]any)["description"]; got != "Start of interval. Example: 2025-06-25T00:00:00Z "+relativeTimeHint {
+		t.Errorf("Expected description with example, got %v", got)
+	}
+	if got := tool.InputSchema.Properties["Verbose"].(map[string]any)["default"]; got != false {
+		t.Errorf("Expected Verbose to default to false, got %v", got)
+	}
+	if len(tool.InputSchema.Required) != 5 {
+		t.Errorf("Expected 5 required parameters, got %v", tool.InputSchema.Required)
+	}
+}
+
+func TestCreateDynamicToolSchemaConstraints(t *testing.T) {
+	minimum := 1.0
+	tool := createDynamicTool("constrained", &config.DynamicQuery{
+		Parameters: []config.DynamicParameter{
+			{Name: "Level", Type: "string", ParamConstraints: caxiom.ParamConstraints{Enum: []string{"error", "warn"}, MaxLength: 5}},
+			{Name: "Limit", Type: "integer", ParamConstraints: caxiom.ParamConstraints{Enum: []string{"10", "100"}, Min: &minimum}},
+			{Name: "Id", Type: "string", ParamConstraints: caxiom.ParamConstraints{Pattern: "^[a-z]+$"}},
+		},
+	})
+
+	level := tool.InputSchema.Properties["Level"].(map[string]any)
+	if !reflect.DeepEqual(level["enum"], []any{"error", "warn"}) || level["maxLength"] != 5 {
+		t.Errorf("Expected Level enum and maxLength, got %+v", level)
+	}
+	limit := tool.InputSchema.Properties["Limit"].(map[string]any)
+	if !reflect.DeepEqual(limit["enum"], []any{10.0, 100.0}) || limit["minimum"] != 1.0 {
+		t.Errorf("Expected numeric Limit enum and minimum, got %+v", limit)
+	}
+	if id := tool.InputSchema.Properties["Id"].(map[string]any); id["pattern"] != "^[a-z]+$" {
+		t.Errorf("Expected Id pattern, got %+v", id)
+	}
+}
+
+func TestCreateDynamicToolSchemaLists(t *testing.T) {
+	tool := createDynamicTool("lists", &config.DynamicQuery{
+		Parameters: []config.DynamicParameter{
+			{Name: "Ids", Type: "string[]", Required: true, ParamConstraints: caxiom.ParamConstraints{Pattern: "^[a-z]+$", MaxItems: 20}},
+			{Name: "Codes", Type: "integer[]", Default: "[500]"},
+		},
+	})
+
+	ids := tool.InputSchema.Properties["Ids"].(map[string]any)
+	if ids["type"] != "array" || ids["maxItems"] != 20 || ids["minItems"] != 1 {
+		t.Errorf("Expected Ids to be an array of 1 to 20 items, got %+v", ids)
+	}
+	if items := ids["items"]; !reflect.DeepEqual(items, map[string]any{"type": "string", "pattern": "^[a-z]+$"}) {
+		t.Errorf("Expected string items with pattern, got %+v", items)
+	}
+	codes := tool.InputSchema.Properties["Codes"].(map[string]any)
+	if codes["maxItems"] != caxiom.DefaultMaxItems || !reflect.DeepEqual(codes["default"], []any{500.0}) {
+		t.Errorf("Expected Codes with default item limit and default, got %+v", codes)
+	}
+	if !reflect.DeepEqual(codes["items"], map[string]any{"type": "integer"}) {
+		t.Errorf("Expected integer items, got %+v", codes["items"])
+	}
+}
+
+func TestCoerceArgumentsLists(t *testing.T) {
+	query := &config.DynamicQuery{
+		Parameters: []config.DynamicParameter{
+			{Name: "Ids", Type: "string[]", Required: true, ParamConstraints: caxiom.ParamConstraints{MaxItems: 2}},
+			{Name: "Codes", Type: "integer[]", Default: "[]"},
+		},
+	}
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{"Ids": []any{"a", "b"}}
+
+	params, err := coerceArguments(query, request, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to coerce arguments: %v", err)
+	}
+	if !reflect.DeepEqual(params["Ids"], []string{"a", "b"}) || !reflect.DeepEqual(params["Codes"], []string{}) {
+		t.Errorf("Unexpected lists: %+v", params)
+	}
+
+	request.Params.Arguments = map[string]any{"Ids": []any{}, "Codes": []any{"x"}}
+	_, err = coerceArguments(query, request, time.Now())
+	if err == nil || !strings.Contains(err.Error(), "parameter 'Ids': must have at least one item") ||
+		!strings.Contains(err.Error(), `parameter 'Codes': item 1 must be an integer, got "x"`) {
+		t.Errorf("Expected list errors, got %v", err)
+	}
+}
+
+func TestCoerceArgumentsConstraints(t *testing.T) {
+	maximum := 500.0
+	query := &config.DynamicQuery{
+		Parameters: []config.DynamicParameter{
+			{Name: "Level", Type: "string", Required: true, ParamConstraints: caxiom.ParamConstraints{Enum: []string{"error", "warn"}}},
+			{Name: "Limit", Type: "integer", Required: true, ParamConstraints: caxiom.ParamConstraints{Max: &maximum}},
+		},
+	}
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{"Level": "info", "Limit": 1000.0}
+
+	_, err := coerceArguments(query, request, time.Now())
+	if err == nil {
+		t.Fatal("Expected constraint errors")
+	}
+	for _, want := range []string{
+		`parameter 'Level': must be one of "error", "warn", got "info"`,
+		"parameter 'Limit': must be at most 500, got 1000",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error %q, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestCoerceArgumentsDefaults(t *testing.T) {
+	query := &config.DynamicQuery{
+		Parameters: []config.DynamicParameter{
+			{Name: "EntityId", Type: "string", Required: true},
+			{Name: "StartTime", Type: "date-time", Default: "2025-06-25T02:00:00+02:00"},
+			{Name: "Limit", Type: "integer", Default: "100"},
+		},
+	}
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{"EntityId": "example-entity-id", "Limit": nil}
+
+	params, err := coerceArguments(query, request, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to coerce arguments: %v", err)
+	}
+	want := map[string]any{
+		"EntityId":  "example-entity-id",
+		"StartTime": "2025-06-25T00:00:00Z",
+		"Limit":     "100",
+	}
+	for name, value := range want {
+		if params[name] != value {
+			t.Errorf("Expected %s = %v, got %v", name, value, params[name])
+		}
+	}
+}
+
+func TestCoerceArgumentsOptionalSections(t *testing.T) {
+	query := &config.DynamicQuery{
+		Parameters: []config.DynamicParameter{
+			{Name: "EntityId", Type: "string", Required: true},
+			{Name: "Region", Type: "string"},
+			{Name: "Service", Type: "string"},
+		},
+	}
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{"EntityId": "example-entity-id", "Service": "api", "Region": nil}
+
+	params, err := coerceArguments(query, request, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to coerce arguments: %v", err)
+	}
+	if _, ok := params["Region"]; ok {
+		t.Errorf("Expected Region without a default to be left out, got %v", params["Region"])
+	}
+	supplied := suppliedArguments(request)
+	if !supplied["EntityId"] || !supplied["Service"] || supplied["Region"] {
+		t.Errorf("Expected EntityId and Service to be supplied, got %v", supplied)
+	}
+}
+
+func TestDynamicQueryHandlerTimeout(t *testing.T) {
+	manager, srv := newTestManager(t)
+	manager.appConfig.Queries.Timeout = 50 * time.Millisecond
+	srv.InjectFault(axiomtest.QueryPath, axiomtest.Fault{Delay: 5 * time.Second})
+
+	start := time.Now()
+	text := callTool(t, manager, "entity_data", entityDataArgs)
+
+	if !strings.Contains(text, "query exceeded 0.05 s") {
+		t.Errorf("Expected timeout message, got:\n%s", text)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected handler to return promptly after timeout, took %v", elapsed)
+	}
+}
+
+func TestDynamicQueryHandlerRequestDeadline(t *testing.T) {
+	manager, srv := newTestManager(t)
+	manager.appConfig.Queries.Timeout = 30 * time.Second
+	srv.InjectFault(axiomtest.QueryPath, axiomtest.Fault{Delay: 5 * time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	text := callToolContext(t, ctx, manager, "entity_data", entityDataArgs)
+
+	if strings.Contains(text, "query exceeded 30 s") || !strings.Contains(text, "the deadline of the tool call expired") {
+		t.Errorf("Expected the request deadline to be reported instead of the tool timeout, got:\n%s", text)
+	}
+}
+
+func TestDynamicQueryHandlerCancelled(t *testing.T) {
+	manager, srv := newTestManager(t)
+	srv.InjectFault(axiomtest.QueryPath, axiomtest.Fault{Delay: 5 * time.Second})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	text := callToolContext(t, ctx, manager, "entity_data", entityDataArgs)
+
+	if text != "query was cancelled" {
+		t.Errorf("Expected cancellation to be reported, got:\n%s", text)
+	}
+}
+
+func TestRunQueryHandlerServerError(t *testing.T) {
+	manager, srv := newTestManager(t)
+	srv.InjectFault(axiomtest.QueryPath, axiomtest.Fault{Status: http.StatusServiceUnavailable, Times: 4})
+
+	text := callTool(t, manager, "run_query", map[string]any{"apl": "['events'] | limit 10"})
+
+	if !strings.Contains(text, "query execution failed") || !strings.Contains(text, "**Kind**: server_error") {
+		t.Errorf("Expected server error, got:\n%s", text)
+	}
+	if srv.QueryCount() != 4 {
+		t.Errorf("Expected server errors to be retried, got %d queries", srv.QueryCount())
+	}
+}
+
+func TestRunQueryHandlerServerErrorRetried(t *testing.T) {
+	manager, srv := newTestManager(t)
+	srv.InjectFault(axiomtest.QueryPath, axiomtest.Fault{Status: http.StatusBadGateway})
+
+	text := callTool(t, manager, "run_query", map[string]any{"apl": "['events'] | limit 10"})
+
+	if !strings.Contains(text, "## Results") {
+		t.Errorf("Expected results after retry, got:\n%s", text)
+	}
+}
+
+func TestRunQueryHandlerSyntaxError(t *testing.T) {
+	manager, srv := newTestManager(t)
+	srv.InjectFault(axiomtest.QueryPath, axiomtest.Fault{
+		Status:  http.StatusBadRequest,
+		Message: "syntax error: unexpected token 'wher' at line 2, column 3",
+	})
+
+	text := callTool(t, manager, "run_query", map[string]any{"apl": "['events']\n| wher id == 1"})
+
+	for _, want := range []string{"**Kind**: apl_syntax", "**Line**: 2", "**Column**: 3", "near line 2, column 3"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in result, got:\n%s", want, text)
+		}
+	}
+	if srv.QueryCount() != 1 {
+		t.Errorf("Expected syntax errors not to be retried, got %d queries", srv.QueryCount())
+	}
+}
+
+func TestRunQueryHandlerPartialResult(t *testing.T) {
+	manager, srv := newTestManager(t)
+	srv.SetResultContaining("['partial']", axiomtest.Result{
+		Fields:  []query.Field{{Name: "n", Type: "integer"}},
+		Rows:    [][]any{{1}},
+		Details: axiom.QueryDetails{BlocksExamined: 8, BlocksCached: 2, IsPartial: true},
+	})
+
+	text := callTool(t, manager, "run_query", map[string]any{"apl": "['partial']"})
+
+	for _, want := range []string{"INCOMPLETE RESULT", "## Warnings", "The result is partial", "- **Blocks examined**: 8 (2 from cache)", "- **Partial**: yes"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in result, got:\n%s", want, text)
+		}
+	}
+}
@@ -5,7 +5,6 @@ import (
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
-	"github.com/roessland/curated-axiom-mcp/pkg/axiom"
 	"github.com/roessland/curated-axiom-mcp/pkg/config"
 	"github.com/roessland/curated-axiom-mcp/pkg/formatter"
 )
@@ -19,7 +18,7 @@ var runQueryTool = mcp.NewTool("run_query",
 	mcp.WithString("apl", mcp.Required()),
 )
 
-func RunQueryHandler(registry *config.Registry, appConfig *config.AppConfig) server.ToolHandlerFunc {
+func RunQueryHandler(profiles *Profiles, pages *PageStore, appConfig *config.AppConfig) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// Log the tool call request
 		logToolCall("run_query", request)
@@ -28,24 +27,22 @@ func RunQueryHandler(registry *config.Registry, appConfig *config.AppConfig) ser
 		if err != nil {
 			return errorResult(err), nil
 		}
-
-		// Create Axiom client
-		clientConfig := &axiom.AxiomConfig{
-			Token:   appConfig.Axiom.Token,
-			OrgID:   appConfig.Axiom.OrgID,
-			Dataset: appConfig.Axiom.Dataset,
-			URL:     appConfig.Axiom.URL,
+		profile, err := profiles.FromRequest(request)
+		if err != nil {
+			return errorResult(err), nil
 		}
-		client := axiom.NewClient(clientConfig)
 
 		// Execute the query
-		result, err := client.ExecuteQuery(apl)
+		timeout := queryTimeout(appConfig, 0)
+		queryCtx, cancel := withQueryTimeout(ctx, timeout)
+		defer cancel()
+
+		result, err := profile.Client.ExecuteQuery(queryCtx, apl)
 		if err != nil {
-			return failedResult("query execution failed"), nil
+			return queryErrorResult(ctx, queryCtx, err, timeout), nil
 		}
 
 		// Format result for LLM
-		llmFormatter := formatter.NewLLMFormatter()
 		formatOptions := formatter.FormatOptions{
 			Format:      "table",
 			LLMFriendly: true,
@@ -53,13 +50,12 @@ func RunQueryHandler(registry *config.Registry, appConfig *config.AppConfig) ser
 			APLQuery:    apl, // Pass the original APL query
 		}
 
-		formatted, err := llmFormatter.Format(result, formatOptions)
+		// Format as markdown/plaintext response
+		textResponse, err := formatPage(ctx, pages, result, formatOptions)
 		if err != nil {
 			return failedResult("failed to format results"), nil
 		}
 
-		// Format as markdown/plaintext response
-		textResponse := formatAsMarkdown(formatted)
 		return successResult(textResponse), nil
 	}
 }
@@ -6,28 +6,25 @@ import (
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
-	"github.com/roessland/curated-axiom-mcp/pkg/axiom"
-	"github.com/roessland/curated-axiom-mcp/pkg/config"
+	"github.com/roessland/curated-axiom-mcp/pkg/utils"
 )
 
 var starredQueriesTool = mcp.NewTool("debug_starred_queries")
 
 // DebugStarredQueriesHandler lists all starred queries in Axiom
-func DebugStarredQueriesHandler(appConfig *config.AppConfig) server.ToolHandlerFunc {
+func DebugStarredQueriesHandler(profiles *Profiles) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// Log the tool call request
 		logToolCall("debug_starred_queries", request)
 		
-		clientConfig := &axiom.AxiomConfig{
-			Token:   appConfig.Axiom.Token,
-			OrgID:   appConfig.Axiom.OrgID,
-			Dataset: appConfig.Axiom.Dataset,
-			URL:     appConfig.Axiom.URL,
+		profile, err := profiles.FromRequest(request)
+		if err != nil {
+			return errorResult(err), nil
 		}
-		client := axiom.NewClient(clientConfig)
-		queries, err := client.StarredQueries()
+
+		queries, err := profile.Client.StarredQueries(ctx)
 		if err != nil {
-			return failedResult("failed to fetch starred queries"), nil
+			return failedResult(formatAxiomError("failed to fetch starred queries", utils.ClassifyAxiomError(err))), nil
 		}
 		if len(queries) == 0 {
 			return successResult("No starred queries found."), nil
@@ -1,7 +1,9 @@
 package cserver
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
@@ -9,10 +11,83 @@ import (
 	"strings"
 	"time"
 
+	"github.com/axiomhq/axiom-go/axiom/query"
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom"
+	"github.com/roessland/curated-axiom-mcp/pkg/config"
 	"github.com/roessland/curated-axiom-mcp/pkg/formatter"
+	"github.com/roessland/curated-axiom-mcp/pkg/utils"
 )
 
+// queryTimeout returns the timeout for a query, preferring the per-tool
+// timeout over the configured default
+func queryTimeout(appConfig *config.AppConfig, toolTimeout time.Duration) time.Duration {
+	if toolTimeout > 0 {
+		return toolTimeout
+	}
+	return appConfig.Queries.Timeout
+}
+
+// withQueryTimeout derives a context that is cancelled after timeout. A zero
+// timeout only inherits cancellation from the parent context.
+func withQueryTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// queryErrorResult converts a query execution error into a tool result,
+// giving the LLM actionable feedback for timeouts, cancellations and errors
+// reported by Axiom. ctx is the request context and queryCtx the context
+// derived from it with the tool timeout, so the timeout is only blamed when
+// it is what stopped the query.
+func queryErrorResult(ctx, queryCtx context.Context, err error, timeout time.Duration) *mcp.CallToolResult {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return failedResult(formatAxiomError("query execution failed", &utils.AxiomError{
+			Kind:    utils.AxiomErrorTimeout,
+			Message: "the deadline of the tool call expired before the query finished",
+			Err:     err,
+		}))
+	case errors.Is(ctx.Err(), context.Canceled):
+		return failedResult("query was cancelled")
+	case errors.Is(queryCtx.Err(), context.DeadlineExceeded):
+		return failedResult(formatAxiomError("query execution failed", &utils.AxiomError{
+			Kind: utils.AxiomErrorTimeout,
+			Message: fmt.Sprintf("query exceeded %g s, narrow the time range or add more filters and try again",
+				timeout.Seconds()),
+			Err: err,
+		}))
+	default:
+		return failedResult(formatAxiomError("query execution failed", utils.ClassifyAxiomError(err)))
+	}
+}
+
+// formatAxiomError formats a classified Axiom error as a structured message
+// the LLM can act on
+func formatAxiomError(title string, axiomErr *utils.AxiomError) string {
+	var builder strings.Builder
+
+	builder.WriteString(title)
+	builder.WriteString("\n\n")
+	builder.WriteString(fmt.Sprintf("- **Kind**: %s\n", axiomErr.Kind))
+	builder.WriteString(fmt.Sprintf("- **Message**: %s\n", axiomErr.Message))
+	if axiomErr.StatusCode != 0 {
+		builder.WriteString(fmt.Sprintf("- **Status**: %d\n", axiomErr.StatusCode))
+	}
+	if axiomErr.Line > 0 {
+		builder.WriteString(fmt.Sprintf("- **Line**: %d\n", axiomErr.Line))
+		builder.WriteString(fmt.Sprintf("- **Column**: %d\n", axiomErr.Column))
+	}
+	if axiomErr.RetryAfter > 0 {
+		builder.WriteString(fmt.Sprintf("- **Retry after**: %.0f s\n", axiomErr.RetryAfter.Seconds()))
+	}
+	builder.WriteString(fmt.Sprintf("- **Hint**: %s\n", axiomErr.Hint()))
+
+	return builder.String()
+}
+
 // writeDebugLog writes debug information to stdout.log file
 func writeDebugLog(content string) {
 	homeDir, err := os.UserHomeDir()
@@ -76,6 +151,15 @@ func formatAsMarkdown(result *formatter.FormattedResult) string {
 	builder.WriteString(result.Summary)
 	builder.WriteString("\n\n")
 	
+	// Warnings come first, so they aren't missed
+	if len(result.Warnings) > 0 {
+		builder.WriteString("## Warnings\n\n")
+		for _, warning := range result.Warnings {
+			builder.WriteString(fmt.Sprintf("- %s\n", warning))
+		}
+		builder.WriteString("\n")
+	}
+
 	// Add APL section if query is available
 	if result.APLQuery != "" {
 		builder.WriteString("## APL\n\n")
@@ -84,6 +168,9 @@ func formatAsMarkdown(result *formatter.FormattedResult) string {
 		builder.WriteString("\n```\n\n")
 	}
 	
+	// Add query cost and status
+	writeQueryStats(&builder, result)
+
 	// Add column statistics if available
 	if columnStats, ok := result.Metadata["column_stats"].(map[string]formatter.ColumnStats); ok && len(columnStats) > 0 {
 		builder.WriteString("## Column Stats\n\n")
@@ -226,6 +313,38 @@ func formatAsMarkdown(result *formatter.FormattedResult) string {
 	return builder.String()
 }
 
+// writeQueryStats writes what the query cost, skipping values Axiom didn't report
+func writeQueryStats(builder *strings.Builder, result *formatter.FormattedResult) {
+	status, _ := result.Metadata["status"].(query.Status)
+	details, _ := result.Metadata["details"].(axiom.QueryDetails)
+	if status.ElapsedTime == 0 && status.RowsExamined == 0 && details.BlocksExamined == 0 {
+		return
+	}
+
+	builder.WriteString("## Query Stats\n\n")
+	if status.ElapsedTime > 0 {
+		builder.WriteString(fmt.Sprintf("- **Elapsed**: %.1f s\n", status.ElapsedTime.Seconds()))
+	}
+	builder.WriteString(fmt.Sprintf("- **Rows examined**: %d\n", status.RowsExamined))
+	builder.WriteString(fmt.Sprintf("- **Rows matched**: %d\n", status.RowsMatched))
+	if details.BlocksExamined > 0 {
+		builder.WriteString(fmt.Sprintf("- **Blocks examined**: %d (%d from cache)\n", details.BlocksExamined, details.BlocksCached))
+	}
+	if details.BytesScanned > 0 {
+		builder.WriteString(fmt.Sprintf("- **Bytes scanned**: %d\n", details.BytesScanned))
+	}
+	if details.NumGroups > 0 {
+		builder.WriteString(fmt.Sprintf("- **Groups**: %d\n", details.NumGroups))
+	}
+	if details.IsPartial {
+		builder.WriteString("- **Partial**: yes\n")
+	}
+	if details.IsEstimate {
+		builder.WriteString("- **Estimate**: yes\n")
+	}
+	builder.WriteString("\n")
+}
+
 // Respond to LLM that tool call was successful
 func successResult(content string) *mcp.CallToolResult {
 	// Check response size and log warning if over 20KB
@@ -3,6 +3,7 @@ package formatter
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/axiomhq/axiom-go/axiom/query"
 	"github.com/roessland/curated-axiom-mcp/pkg/axiom"
@@ -26,7 +27,7 @@ func (f *LLMFormatter) Format(result *axiom.QueryResult, options FormatOptions)
 
 	formatted := &FormattedResult{
 		Count:    totalRows,
-		Warnings: []string{}, // No warnings in tabular format
+		Warnings: generateWarnings(result),
 		Metadata: make(map[string]interface{}),
 		APLQuery: options.APLQuery,
 	}
@@ -36,6 +37,7 @@ func (f *LLMFormatter) Format(result *axiom.QueryResult, options FormatOptions)
 		formatted.Metadata["fields"] = result.Tables[0].Fields
 	}
 	formatted.Metadata["status"] = result.Status
+	formatted.Metadata["details"] = result.Details
 
 	// Format as CSV data (primary format for /_apl endpoint)
 	csvData := f.formatAsCSV(result, options)
@@ -43,8 +45,33 @@ func (f *LLMFormatter) Format(result *axiom.QueryResult, options FormatOptions)
 	
 	formatted.Data = csvData
 	formatted.Summary = f.generateTableSummary(result, options)
+	if isIncomplete(result) {
+		// State plainly that the data is incomplete, so it isn't treated as complete
+		formatted.Summary = "INCOMPLETE RESULT: " + formatted.Summary + " Axiom did not return all matching data, see Warnings."
+	}
 	formatted.Metadata["column_stats"] = columnStats
 
+	// Echo the absolute time window, since relative times depend on the clock
+	if len(options.ResolvedTimes) > 0 {
+		parts := make([]string, len(options.ResolvedTimes))
+		for i, resolved := range options.ResolvedTimes {
+			value := resolved.Time.UTC().Format(time.RFC3339Nano)
+			parts[i] = resolved.Name + "=" + value
+			if resolved.Input != "" && resolved.Input != value {
+				parts[i] += fmt.Sprintf(" (from %q)", resolved.Input)
+			}
+		}
+		formatted.Summary += " Date-time arguments in UTC: " + strings.Join(parts, ", ") + "."
+	}
+
+	// Tell the LLM how fresh cached data is
+	if !options.CachedAt.IsZero() {
+		age := time.Since(options.CachedAt).Round(time.Second)
+		formatted.Summary += fmt.Sprintf(" Served from cache: data is %.0f s old (fetched at %s).",
+			age.Seconds(), options.CachedAt.UTC().Format(time.RFC3339))
+		formatted.Metadata["cached_at"] = options.CachedAt
+	}
+
 	return formatted, nil
 }
 
@@ -87,18 +114,19 @@ func (f *LLMFormatter) formatAsCSV(result *axiom.QueryResult, options FormatOpti
 		totalRows = len(table.Columns[0])
 	}
 
-	// Limit rows if specified
-	maxRows := totalRows
-	if options.MaxRows > 0 && totalRows > options.MaxRows {
-		maxRows = options.MaxRows
-	}
+	// Limit rows to the requested page
+	start, end := pageBounds(totalRows, options)
 
 	// Write data rows using the Table.Rows() iterator
 	rowIndex := 0
 	for row := range table.Rows() {
-		if rowIndex >= maxRows {
+		if rowIndex >= end {
 			break
 		}
+		if rowIndex < start {
+			rowIndex++
+			continue
+		}
 		
 		// Write row values
 		for i, value := range row {
@@ -137,21 +165,22 @@ func (f *LLMFormatter) formatTable(result *axiom.QueryResult, options FormatOpti
 		totalRows = len(table.Columns[0])
 	}
 
-	// Limit rows if specified
-	maxRows := totalRows
-	if options.MaxRows > 0 && totalRows > options.MaxRows {
-		maxRows = options.MaxRows
-	}
+	// Limit rows to the requested page
+	start, end := pageBounds(totalRows, options)
 
 	// Use the Table.Rows() iterator to get properly formatted rows
-	rows := make([][]string, 0, maxRows)
+	rows := make([][]string, 0, end-start)
 	rowIndex := 0
 	
 	// Iterate through rows using the table iterator
 	for row := range table.Rows() {
-		if rowIndex >= maxRows {
+		if rowIndex >= end {
 			break
 		}
+		if rowIndex < start {
+			rowIndex++
+			continue
+		}
 		
 		// Convert row values to CSV-formatted strings
 		csvRow := make([]string, len(row))
@@ -170,6 +199,89 @@ func (f *LLMFormatter) formatTable(result *axiom.QueryResult, options FormatOpti
 	}
 }
 
+// rowLimitCodes are the codes of messages that Axiom returns when a row limit
+// cut the result short
+var rowLimitCodes = map[string]bool{
+	"default_limit_warning":           true,
+	"license_limit_for_query_warning": true,
+}
+
+// generateWarnings explains query status flags and messages that affect how
+// the result should be read
+func generateWarnings(result *axiom.QueryResult) []string {
+	warnings := []string{}
+	if result.Details.IsPartial {
+		warnings = append(warnings, "The result is partial: Axiom stopped before scanning all matching data, so rows and counts are incomplete. Narrow the time range or add filters and run the query again.")
+	}
+	if result.Details.IsEstimate {
+		warnings = append(warnings, "Aggregated values are estimates, not exact counts.")
+	}
+
+	for _, message := range reportedMessages(result) {
+		switch {
+		case isQueryTimeLimit(message):
+			warnings = append(warnings, fmt.Sprintf("The query hit Axiom's maximum query time and was stopped early (%s). Narrow the time range or add filters.", message.Text))
+		case isRowLimit(message):
+			warnings = append(warnings, fmt.Sprintf("A row limit was hit (%s), so not all matching rows were returned. Aggregate, add filters or set an explicit limit.", message.Text))
+		default:
+			warnings = append(warnings, fmt.Sprintf("Axiom %s: %s (%s)", message.Priority, message.Text, message.Code))
+		}
+	}
+
+	return warnings
+}
+
+// reportedMessages returns the query messages that are important enough to
+// show, in order
+func reportedMessages(result *axiom.QueryResult) []axiom.QueryMessage {
+	var reported []axiom.QueryMessage
+	for _, message := range result.Details.Messages {
+		switch strings.ToLower(message.Priority) {
+		case "trace", "debug", "info":
+			continue
+		}
+		reported = append(reported, message)
+	}
+	return reported
+}
+
+// isQueryTimeLimit reports whether a message says the query hit the maximum
+// query time
+func isQueryTimeLimit(message axiom.QueryMessage) bool {
+	return strings.Contains(strings.ToLower(message.Code), "query_time")
+}
+
+// isRowLimit reports whether a message says a row limit was hit
+func isRowLimit(message axiom.QueryMessage) bool {
+	return rowLimitCodes[strings.ToLower(message.Code)]
+}
+
+// isIncomplete reports whether Axiom returned less data than the query
+// matched. It uses the same messages as generateWarnings, so an incomplete
+// result always has a warning that explains why.
+func isIncomplete(result *axiom.QueryResult) bool {
+	if result.Details.IsPartial {
+		return true
+	}
+	for _, message := range reportedMessages(result) {
+		if isQueryTimeLimit(message) || isRowLimit(message) {
+			return true
+		}
+	}
+	return false
+}
+
+// pageBounds returns the range of rows [start, end) to show for the given
+// offset and row limit
+func pageBounds(totalRows int, options FormatOptions) (start, end int) {
+	start = min(max(options.Offset, 0), totalRows)
+	end = totalRows
+	if options.MaxRows > 0 && end-start > options.MaxRows {
+		end = start + options.MaxRows
+	}
+	return start, end
+}
+
 // generateTableSummary creates a human-readable summary of table data
 func (f *LLMFormatter) generateTableSummary(result *axiom.QueryResult, options FormatOptions) string {
 	if len(result.Tables) == 0 {
@@ -182,10 +294,7 @@ func (f *LLMFormatter) generateTableSummary(result *axiom.QueryResult, options F
 		totalRows = len(table.Columns[0])
 	}
 
-	displayRows := totalRows
-	if options.MaxRows > 0 && totalRows > options.MaxRows {
-		displayRows = options.MaxRows
-	}
+	start, end := pageBounds(totalRows, options)
 
 	if totalRows == 0 {
 		// Add query performance info for no results
@@ -209,8 +318,13 @@ func (f *LLMFormatter) generateTableSummary(result *axiom.QueryResult, options F
 	}
 	
 	// Add display info
-	if displayRows < totalRows {
-		summary.WriteString(fmt.Sprintf(". Showing first %d", displayRows))
+	switch {
+	case start >= totalRows:
+		summary.WriteString(". No rows left to show")
+	case start > 0:
+		summary.WriteString(fmt.Sprintf(". Showing rows %d-%d", start+1, end))
+	case end < totalRows:
+		summary.WriteString(fmt.Sprintf(". Showing first %d", end))
 	}
 	
 	// Add field count
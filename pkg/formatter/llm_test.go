@@ -0,0 +1,186 @@
+package formatter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/axiomhq/axiom-go/axiom/query"
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom"
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom/axiomtest"
+)
+
+func TestFormatFakeServerResult(t *testing.T) {
+	srv := axiomtest.NewServer(t)
+	if err := srv.LoadFixtures(axiomtest.DefaultFixtures()); err != nil {
+		t.Fatalf("Failed to load fixtures: %v", err)
+	}
+
+	result, err := srv.Client().ExecuteQuery(context.Background(), "['events'] | limit 10")
+	if err != nil {
+		t.Fatalf("Failed to execute query: %v", err)
+	}
+
+	formatted, err := NewLLMFormatter().Format(result, DefaultFormatOptions())
+	if err != nil {
+		t.Fatalf("Failed to format result: %v", err)
+	}
+
+	if formatted.Count != 4 {
+		t.Errorf("Expected count 4, got %d", formatted.Count)
+	}
+	if formatted.Summary != "Found 4 records in 0.1 s with 4 fields." {
+		t.Errorf("Unexpected summary: %s", formatted.Summary)
+	}
+
+	csv, ok := formatted.Data.(string)
+	if !ok {
+		t.Fatalf("Expected CSV data, got %T", formatted.Data)
+	}
+	lines := strings.Split(strings.TrimSpace(csv), "\n")
+	if lines[0] != "_time,id,event,duration_ms" {
+		t.Errorf("Unexpected header: %s", lines[0])
+	}
+	if len(lines) != 3+4 {
+		t.Errorf("Expected header, types, separator and 4 rows, got %d lines", len(lines))
+	}
+
+	stats := formatted.Metadata["column_stats"].(map[string]ColumnStats)
+	if stats["event"].UniqueValues["upload"] != 2 {
+		t.Errorf("Expected 'upload' to occur twice, got %d", stats["event"].UniqueValues["upload"])
+	}
+}
+
+func TestFormatTruncatesRows(t *testing.T) {
+	srv := axiomtest.NewServer(t)
+	rows := make([][]any, 150)
+	for i := range rows {
+		rows[i] = []any{i}
+	}
+	srv.SetDefaultResult(axiomtest.Result{
+		Fields: []query.Field{{Name: "n", Type: "integer"}},
+		Rows:   rows,
+	})
+
+	result, err := srv.Client().ExecuteQuery(context.Background(), "['numbers']")
+	if err != nil {
+		t.Fatalf("Failed to execute query: %v", err)
+	}
+
+	formatted, err := NewLLMFormatter().Format(result, DefaultFormatOptions())
+	if err != nil {
+		t.Fatalf("Failed to format result: %v", err)
+	}
+
+	if !strings.Contains(formatted.Summary, "Showing first 100") {
+		t.Errorf("Expected summary to mention truncation, got: %s", formatted.Summary)
+	}
+	if lines := strings.Count(formatted.Data.(string), "\n"); lines != 3+100 {
+		t.Errorf("Expected 100 data rows, got %d lines", lines)
+	}
+}
+
+func TestFormatOffset(t *testing.T) {
+	srv := axiomtest.NewServer(t)
+	rows := make([][]any, 150)
+	for i := range rows {
+		rows[i] = []any{i}
+	}
+	srv.SetDefaultResult(axiomtest.Result{
+		Fields: []query.Field{{Name: "n", Type: "integer"}},
+		Rows:   rows,
+	})
+
+	result, err := srv.Client().ExecuteQuery(context.Background(), "['numbers']")
+	if err != nil {
+		t.Fatalf("Failed to execute query: %v", err)
+	}
+
+	options := DefaultFormatOptions()
+	options.Offset = 100
+	formatted, err := NewLLMFormatter().Format(result, options)
+	if err != nil {
+		t.Fatalf("Failed to format result: %v", err)
+	}
+
+	if !strings.Contains(formatted.Summary, "Showing rows 101-150") {
+		t.Errorf("Expected summary to mention the page, got: %s", formatted.Summary)
+	}
+	lines := strings.Split(strings.TrimSpace(formatted.Data.(string)), "\n")
+	if len(lines) != 3+50 || lines[3] != "100" || lines[len(lines)-1] != "149" {
+		t.Errorf("Expected rows 100-149, got %d lines starting with %q", len(lines), lines[3])
+	}
+}
+
+func TestFormatWarnings(t *testing.T) {
+	result := &axiom.QueryResult{
+		Result: query.Result{
+			Tables: []query.Table{{
+				Fields:  []query.Field{{Name: "n", Type: "integer"}},
+				Columns: []query.Column{{1, 2}},
+			}},
+		},
+		Details: axiom.QueryDetails{
+			IsPartial:  true,
+			IsEstimate: true,
+			Messages: []axiom.QueryMessage{
+				{Priority: "warn", Code: "default_limit_warning", Text: "limit 1000 applied"},
+				{Priority: "info", Code: "cache_hit", Text: "served from cache"},
+			},
+		},
+	}
+
+	formatted, err := NewLLMFormatter().Format(result, DefaultFormatOptions())
+	if err != nil {
+		t.Fatalf("Failed to format result: %v", err)
+	}
+
+	if !strings.HasPrefix(formatted.Summary, "INCOMPLETE RESULT: ") {
+		t.Errorf("Expected summary to state the result is incomplete, got: %s", formatted.Summary)
+	}
+	if len(formatted.Warnings) != 3 {
+		t.Fatalf("Expected 3 warnings, got %d: %v", len(formatted.Warnings), formatted.Warnings)
+	}
+	if !strings.Contains(formatted.Warnings[2], "A row limit was hit (limit 1000 applied)") {
+		t.Errorf("Expected row limit warning, got: %s", formatted.Warnings[2])
+	}
+}
+
+func TestFormatNoWarnings(t *testing.T) {
+	formatted, err := NewLLMFormatter().Format(&axiom.QueryResult{}, DefaultFormatOptions())
+	if err != nil {
+		t.Fatalf("Failed to format result: %v", err)
+	}
+	if len(formatted.Warnings) != 0 || strings.Contains(formatted.Summary, "INCOMPLETE") {
+		t.Errorf("Expected complete result without warnings, got %q, %v", formatted.Summary, formatted.Warnings)
+	}
+}
+
+func TestFormatUnreportedLimitMessage(t *testing.T) {
+	result := &axiom.QueryResult{
+		Result: query.Result{
+			Tables: []query.Table{{
+				Fields:  []query.Field{{Name: "n", Type: "integer"}},
+				Columns: []query.Column{{1, 2}},
+			}},
+		},
+		Details: axiom.QueryDetails{
+			Messages: []axiom.QueryMessage{
+				{Priority: "info", Code: "default_limit_warning", Text: "limit 1000 applied"},
+				{Priority: "warn", Code: "rate_limit_remaining", Text: "90 queries left"},
+			},
+		},
+	}
+
+	formatted, err := NewLLMFormatter().Format(result, DefaultFormatOptions())
+	if err != nil {
+		t.Fatalf("Failed to format result: %v", err)
+	}
+
+	if strings.Contains(formatted.Summary, "INCOMPLETE") {
+		t.Errorf("Expected complete result, got: %s", formatted.Summary)
+	}
+	if len(formatted.Warnings) != 1 || !strings.Contains(formatted.Warnings[0], "Axiom warn: 90 queries left (rate_limit_remaining)") {
+		t.Errorf("Expected only the warn message as a warning, got: %v", formatted.Warnings)
+	}
+}
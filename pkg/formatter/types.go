@@ -1,6 +1,10 @@
 package formatter
 
-import "github.com/roessland/curated-axiom-mcp/pkg/axiom"
+import (
+	"time"
+
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom"
+)
 
 // FormattedResult represents a formatted query result
 type FormattedResult struct {
@@ -43,10 +47,22 @@ type SummaryResult struct {
 
 // FormatOptions controls how results are formatted
 type FormatOptions struct {
-	Format      string // "table", "json", "summary", "timeseries"
-	LLMFriendly bool   // Whether to optimize for LLM consumption
-	MaxRows     int    // Maximum number of rows to include
-	APLQuery    string // The APL query that was executed (for debugging/transparency)
+	Format      string    // "table", "json", "summary", "timeseries"
+	LLMFriendly bool      // Whether to optimize for LLM consumption
+	MaxRows     int       // Maximum number of rows to include
+	Offset      int       // Number of rows to skip, for fetching later pages
+	APLQuery    string    // The APL query that was executed (for debugging/transparency)
+	CachedAt    time.Time // When a cached result was fetched, zero for fresh results
+
+	ResolvedTimes []ResolvedTime // Date-time arguments of the query, echoed so the time window is unambiguous
+}
+
+// ResolvedTime is a date-time argument as given by the agent and as resolved
+// to an absolute time
+type ResolvedTime struct {
+	Name  string
+	Input string // Argument as given, e.g. yesterday
+	Time  time.Time
 }
 
 // DefaultFormatOptions returns sensible defaults
@@ -0,0 +1,215 @@
+// Package querylint checks curated starred queries for problems that don't
+// stop them from loading, or that only show up when an agent calls the tool,
+// such as parameters without a placeholder or queries without a time filter.
+package querylint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/roessland/curated-axiom-mcp/pkg/caxiom"
+	"github.com/roessland/curated-axiom-mcp/pkg/config"
+	"github.com/roessland/curated-axiom-mcp/pkg/querysync"
+)
+
+// Rules of the problems reported by Lint
+const (
+	RuleInvalidQuery       = "invalid-query"       // The query does not parse, so it is not loaded
+	RuleMetadata           = "metadata"            // Unknown fields in metadata without a Version
+	RuleUnusedParam        = "unused-param"        // Metadata param without a placeholder
+	RuleUnknownPlaceholder = "unknown-placeholder" // Placeholder without a metadata param
+	RuleUnannotatedDeclare = "unannotated-declare" // Declared query parameter without a ///param= annotation
+	RuleDuplicateToolName  = "duplicate-tool-name" // ToolName of several queries
+	RuleInvalidToolName    = "invalid-tool-name"   // Tool name that MCP clients may reject
+	RuleMissingDescription = "missing-description" // No Description for the agent
+	RuleNoTimeFilter       = "no-time-filter"      // No where clause on _time
+	RuleNoLimit            = "no-limit"            // No limit, take or top
+)
+
+// timeFilterRegex matches a where clause on _time
+var timeFilterRegex = regexp.MustCompile(`\bwhere\b[^|]*\b_time\b`)
+
+// limitRegex matches an operator that limits the number of rows
+var limitRegex = regexp.MustCompile(`\|\s*(limit|take|top)\b`)
+
+// Query is a starred query to lint
+type Query struct {
+	Name string // Name of the starred query
+	APL  string
+	Path string // File of the query, empty for starred queries in Axiom
+
+	// Profile prefixes the tool names, like the server does when several
+	// profiles are loaded. Empty if tool names are not prefixed.
+	Profile string
+}
+
+// Problem is a problem of a query
+type Problem struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	Line    int    `json:"line,omitempty"` // Line in the query or file, 0 if unknown
+}
+
+// Result is the problems of a query
+type Result struct {
+	Name     string    `json:"name"`
+	Path     string    `json:"path,omitempty"`
+	ToolName string    `json:"tool_name,omitempty"` // Including the profile prefix
+	Problems []Problem `json:"problems"`
+}
+
+// Lint checks queries and returns a result for each query, in order
+func Lint(queries []Query) []Result {
+	results := make([]Result, len(queries))
+	byToolName := make(map[string][]int)
+	for i, query := range queries {
+		results[i] = lintQuery(query)
+		if toolName := results[i].ToolName; toolName != "" {
+			byToolName[toolName] = append(byToolName[toolName], i)
+		}
+	}
+
+	for toolName, indexes := range byToolName {
+		if len(indexes) < 2 {
+			continue
+		}
+		for _, i := range indexes {
+			var others []string
+			for _, j := range indexes {
+				if j != i {
+					others = append(others, describe(queries[j]))
+				}
+			}
+			results[i].Problems = append(results[i].Problems, Problem{
+				Rule:    RuleDuplicateToolName,
+				Message: fmt.Sprintf("tool name %s is also used by %s", toolName, strings.Join(others, ", ")),
+			})
+		}
+	}
+	return results
+}
+
+// describe names a query in messages
+func describe(query Query) string {
+	if query.Path != "" {
+		return query.Path
+	}
+	return fmt.Sprintf("%q", query.Name)
+}
+
+// lintQuery checks a single query
+func lintQuery(query Query) Result {
+	result := Result{Name: query.Name, Path: query.Path, Problems: []Problem{}}
+	report := func(rule string, line int, format string, args ...any) {
+		result.Problems = append(result.Problems, Problem{Rule: rule, Message: fmt.Sprintf(format, args...), Line: line})
+	}
+
+	parsed, err := caxiom.ParseStarredQuery(query.Name, query.APL)
+	if err != nil {
+		report(RuleInvalidQuery, 0, "%v", err)
+	} else {
+		if toolName := parsed.Metadata.CuratedAxiomMCP.ToolName; toolName != "" {
+			result.ToolName = publishedToolName(query, toolName)
+		}
+		lintMetadata(query, parsed, report)
+	}
+
+	if apl, err := caxiom.StripComments(query.APL); err == nil {
+		if !timeFilterRegex.MatchString(apl) {
+			report(RuleNoTimeFilter, 0, "no where clause on _time, so the query may scan the whole dataset")
+		}
+		if !limitRegex.MatchString(apl) {
+			report(RuleNoLimit, 0, "no limit, take or top, so the query may return more rows than the agent can use")
+		}
+	}
+	return result
+}
+
+// publishedToolName returns the name the server publishes a tool of the query as
+func publishedToolName(query Query, toolName string) string {
+	if query.Profile == "" {
+		return toolName
+	}
+	return config.ProfileToolName(query.Profile, toolName)
+}
+
+// lintMetadata checks the metadata of a parsed query against its template
+func lintMetadata(query Query, parsed *caxiom.ParsedQuery, report func(rule string, line int, format string, args ...any)) {
+	metadata := parsed.Metadata.CuratedAxiomMCP
+	for _, warning := range parsed.Warnings {
+		report(RuleMetadata, 0, "%s (add Version: %d to make this an error)", warning, caxiom.MetadataVersion)
+	}
+
+	switch {
+	case metadata.ToolName == "" && !config.ValidToolName(publishedToolName(query, parsed.Name)):
+		report(RuleInvalidToolName, 0, "no ToolName, and the tool is named %q after the starred query, which is not a valid tool name", publishedToolName(query, parsed.Name))
+	case metadata.ToolName != "" && !config.ValidToolName(publishedToolName(query, metadata.ToolName)):
+		report(RuleInvalidToolName, 0, "tool name %q must be 1 to %d letters, digits, underscores or hyphens", publishedToolName(query, metadata.ToolName), config.MaxToolNameLength)
+	}
+	if strings.TrimSpace(metadata.Description) == "" {
+		report(RuleMissingDescription, 0, "no Description, so the agent has to guess what the tool does")
+	}
+
+	placeholders, err := caxiom.Placeholders(parsed.TemplateAPL)
+	if err != nil {
+		// Checked by ParseStarredQuery
+		return
+	}
+	params := make(map[string]bool, len(metadata.Params))
+	for _, param := range metadata.Params {
+		params[param.Name] = true
+	}
+	used := make(map[string]bool, len(placeholders))
+	for _, name := range placeholders {
+		used[name] = true
+		if !params[name] {
+			report(RuleUnknownPlaceholder, 0, "placeholder {{.%s}} has no param in the metadata, so every call fails", name)
+		}
+	}
+	for _, param := range metadata.Params {
+		if !used[param.Name] {
+			report(RuleUnusedParam, 0, "param %s has no {{.%s}} placeholder, so its argument is ignored", param.Name, param.Name)
+		}
+	}
+
+	for _, dp := range parsed.Declared {
+		if dp.Annotation == "" {
+			report(RuleUnannotatedDeclare, dp.Pos.Line, "declared parameter %s has no ///param= annotation, so it always has its default", dp.Name)
+		}
+	}
+}
+
+// ReadDir reads the curated query files in dir. The APL of each query is the
+// whole file, so lines of problems match the file.
+func ReadDir(dir string) ([]Query, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.apl"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list query files: %w", err)
+	}
+	sort.Strings(paths)
+
+	queries := make([]Query, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read query file: %w", err)
+		}
+		name, _ := querysync.Decode(path, data)
+		apl := strings.ReplaceAll(string(data), "\r\n", "\n")
+		queries = append(queries, Query{Name: name, APL: apl, Path: path})
+	}
+	return queries, nil
+}
+
+// ProblemCount returns the number of problems of all results
+func ProblemCount(results []Result) int {
+	count := 0
+	for _, result := range results {
+		count += len(result.Problems)
+	}
+	return count
+}
@@ -0,0 +1,138 @@
+package querylint_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/roessland/curated-axiom-mcp/pkg/querylint"
+	"github.com/roessland/curated-axiom-mcp/pkg/querysync"
+)
+
+const cleanAPL = `declare query_parameters (
+    q_entity_id:string = 'example-entity-id' ///param='{{.EntityId}}'
+);
+['events']
+| where _time > ago(1d)
+| where id == q_entity_id
+| limit 100
+
+// CuratedAxiomMCP:
+//   Version: 1
+//   ToolName: entity_events
+//   Description: Recent events of an entity
+//   Params:
+//     - Name: EntityId`
+
+const sloppyAPL = `declare query_parameters (
+    q_level:string = 'error'
+);
+['events']
+| where id == '{{.Id}}' and level == q_level
+
+// CuratedAxiomMCP:
+//   ToolName: entity events
+//   Params:
+//     - Name: EntityId
+//       Required: false
+//       Default: x
+//   Descripton: typo`
+
+func rules(result querylint.Result) []string {
+	var rules []string
+	for _, problem := range result.Problems {
+		rules = append(rules, problem.Rule)
+	}
+	return rules
+}
+
+func TestLint(t *testing.T) {
+	results := querylint.Lint([]querylint.Query{
+		{Name: "Clean", APL: cleanAPL},
+		{Name: "Sloppy", APL: sloppyAPL},
+		{Name: "Broken", APL: "['events'] | where _time > ago(1d) | take 1\n\n// CuratedAxiomMCP:\n//   Version: 1\n//   Param: []"},
+	})
+
+	if len(results[0].Problems) != 0 {
+		t.Errorf("Expected no problems for the clean query, got %+v", results[0].Problems)
+	}
+
+	want := []string{
+		querylint.RuleMetadata,
+		querylint.RuleInvalidToolName,
+		querylint.RuleMissingDescription,
+		querylint.RuleUnknownPlaceholder,
+		querylint.RuleUnusedParam,
+		querylint.RuleUnannotatedDeclare,
+		querylint.RuleNoTimeFilter,
+		querylint.RuleNoLimit,
+	}
+	if got := rules(results[1]); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected rules %v, got %v", want, got)
+	}
+	for _, problem := range results[1].Problems {
+		if problem.Rule == querylint.RuleUnannotatedDeclare && (problem.Line != 2 || !strings.Contains(problem.Message, "q_level")) {
+			t.Errorf("Expected q_level on line 2, got %+v", problem)
+		}
+		if problem.Rule == querylint.RuleMetadata && !strings.Contains(problem.Message, "line 13: unknown field Descripton") {
+			t.Errorf("Expected the misspelled Description, got %+v", problem)
+		}
+	}
+
+	if got := rules(results[2]); strings.Join(got, " ") != querylint.RuleInvalidQuery {
+		t.Errorf("Expected only an invalid query, got %+v", results[2].Problems)
+	}
+	if querylint.ProblemCount(results) != len(want)+1 {
+		t.Errorf("Expected %d problems, got %d", len(want)+1, querylint.ProblemCount(results))
+	}
+}
+
+func TestLintDuplicateToolNames(t *testing.T) {
+	results := querylint.Lint([]querylint.Query{
+		{Name: "First", APL: cleanAPL, Path: "queries/first.apl"},
+		{Name: "Second", APL: cleanAPL, Path: "queries/second.apl"},
+	})
+	for i, other := range []string{"queries/second.apl", "queries/first.apl"} {
+		problems := results[i].Problems
+		if len(problems) != 1 || problems[0].Rule != querylint.RuleDuplicateToolName || !strings.Contains(problems[0].Message, other) {
+			t.Errorf("Expected a duplicate of %s, got %+v", other, problems)
+		}
+	}
+}
+
+func TestReadDirKeepsFileLines(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "entity_events.apl"), querysync.Encode("Entity events", sloppyAPL), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	queries, err := querylint.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read queries: %v", err)
+	}
+	if len(queries) != 1 || queries[0].Name != "Entity events" {
+		t.Fatalf("Unexpected queries: %+v", queries)
+	}
+	for _, problem := range querylint.Lint(queries)[0].Problems {
+		if problem.Rule == querylint.RuleUnannotatedDeclare && problem.Line != 3 {
+			t.Errorf("Expected the line in the file, got %+v", problem)
+		}
+	}
+}
+
+func TestLintProfileToolNames(t *testing.T) {
+	// Valid on its own, but too long once prefixed with the profile name
+	long := strings.Replace(cleanAPL, "ToolName: entity_events", "ToolName: "+strings.Repeat("x", 60), 1)
+	results := querylint.Lint([]querylint.Query{
+		{Name: "Clean", APL: cleanAPL, Profile: "prod"},
+		{Name: "Long", APL: long, Profile: "prod"},
+	})
+
+	if results[0].ToolName != "prod_entity_events" || len(results[0].Problems) != 0 {
+		t.Errorf("Expected prod_entity_events without problems, got %+v", results[0])
+	}
+	if got := rules(results[1]); strings.Join(got, " ") != querylint.RuleInvalidToolName {
+		t.Errorf("Expected an invalid tool name, got %+v", results[1].Problems)
+	}
+}
@@ -0,0 +1,182 @@
+// Package querysync mirrors curated starred queries to and from a directory
+// of .apl files, so curated queries can be reviewed and versioned like code.
+//
+// Each file holds the APL of one starred query. The first line records the
+// name of the starred query, since the file name is derived from the tool name:
+//
+//	// starred-query: Entity data
+//	declare query_parameters (...);
+//	...
+package querysync
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom"
+	"github.com/roessland/curated-axiom-mcp/pkg/caxiom"
+)
+
+// nameHeader prefixes the line holding the starred query name
+const nameHeader = "// starred-query: "
+
+// fileExt is the extension of curated query files
+const fileExt = ".apl"
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// File is a curated query stored in a directory
+type File struct {
+	Name string // Name of the starred query
+	APL  string
+	Path string
+}
+
+// ChangeKind is the kind of change needed to make Axiom match the directory
+type ChangeKind string
+
+const (
+	Create ChangeKind = "create" // Query only exists in the directory
+	Update ChangeKind = "update" // Query differs between the directory and Axiom
+	Delete ChangeKind = "delete" // Query only exists in Axiom
+)
+
+// Change is a difference between the directory and Axiom
+type Change struct {
+	Kind   ChangeKind
+	Name   string
+	Local  *File               // Nil for Delete, except for the file created by Pull
+	Remote *axiom.StarredQuery // Nil for Create
+}
+
+// IsCurated reports whether a starred query is a CuratedAxiomMCP query
+func IsCurated(sq axiom.StarredQuery) bool {
+	return isCuratedAPL(sq.Query.APL)
+}
+
+// isCuratedAPL reports whether APL has the CuratedAxiomMCP marker
+func isCuratedAPL(apl string) bool {
+	return strings.Contains(apl, "CuratedAxiomMCP:")
+}
+
+// FileName returns the file name of a curated query. It is the tool name if
+// the metadata declares one, and otherwise derived from the query name.
+func FileName(name, apl string) string {
+	base := name
+	if parsed, err := caxiom.ParseStarredQuery(name, apl); err == nil && parsed.Metadata.CuratedAxiomMCP.ToolName != "" {
+		base = parsed.Metadata.CuratedAxiomMCP.ToolName
+	}
+	base = strings.Trim(unsafeFileChars.ReplaceAllString(base, "_"), "_.")
+	if base == "" {
+		base = "query"
+	}
+	return base + fileExt
+}
+
+// Encode returns the file content for a starred query
+func Encode(name, apl string) []byte {
+	return []byte(nameHeader + name + "\n" + normalize(apl) + "\n")
+}
+
+// Decode returns the starred query name and APL of a file. Files without a
+// name header are named after the file.
+func Decode(path string, data []byte) (name, apl string) {
+	content := strings.ReplaceAll(string(data), "\r\n", "\n")
+	first, rest, _ := strings.Cut(content, "\n")
+	if strings.HasPrefix(first, nameHeader) {
+		return strings.TrimSpace(strings.TrimPrefix(first, nameHeader)), normalize(rest)
+	}
+	return strings.TrimSuffix(filepath.Base(path), fileExt), normalize(content)
+}
+
+// ReadDir reads all curated query files in dir
+func ReadDir(dir string) ([]File, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+fileExt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list query files: %w", err)
+	}
+	sort.Strings(paths)
+
+	files := make([]File, 0, len(paths))
+	seen := make(map[string]string)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read query file: %w", err)
+		}
+		name, apl := Decode(path, data)
+		if other, ok := seen[name]; ok {
+			return nil, fmt.Errorf("starred query %q is defined in both %s and %s", name, other, path)
+		}
+		seen[name] = path
+		files = append(files, File{Name: name, APL: apl, Path: path})
+	}
+	return files, nil
+}
+
+// WriteFile writes a starred query to dir and returns the file path
+func WriteFile(dir string, sq axiom.StarredQuery) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create query directory: %w", err)
+	}
+	path := filepath.Join(dir, FileName(sq.Name, sq.Query.APL))
+	if err := os.WriteFile(path, Encode(sq.Name, sq.Query.APL), 0644); err != nil {
+		return "", fmt.Errorf("failed to write query file: %w", err)
+	}
+	return path, nil
+}
+
+// Plan returns the changes needed to make the curated starred queries in
+// Axiom match the directory, sorted by query name. Starred queries without
+// the CuratedAxiomMCP marker are never changed: it is an error if a file has
+// no marker, or if it has the name of a starred query without one, since
+// pushing it would create a duplicate on every run.
+func Plan(local []File, remote []axiom.StarredQuery) ([]Change, error) {
+	remoteByName := make(map[string]*axiom.StarredQuery)
+	for i := range remote {
+		sq := &remote[i]
+		// Keep the first of several starred queries with the same name
+		if _, ok := remoteByName[sq.Name]; !ok {
+			remoteByName[sq.Name] = sq
+		}
+	}
+
+	var changes []Change
+	for i := range local {
+		file := &local[i]
+		if !isCuratedAPL(file.APL) {
+			return nil, fmt.Errorf("%s has no CuratedAxiomMCP metadata, add it or remove the file", file.Path)
+		}
+		sq, ok := remoteByName[file.Name]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Kind: Create, Name: file.Name, Local: file})
+		case !IsCurated(*sq):
+			return nil, fmt.Errorf("%s is named after starred query %q, which has no CuratedAxiomMCP metadata, rename one of them", file.Path, file.Name)
+		case normalize(sq.Query.APL) != file.APL:
+			changes = append(changes, Change{Kind: Update, Name: file.Name, Local: file, Remote: sq})
+		}
+		delete(remoteByName, file.Name)
+	}
+	for name, sq := range remoteByName {
+		if IsCurated(*sq) {
+			changes = append(changes, Change{Kind: Delete, Name: name, Remote: sq})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+	return changes, nil
+}
+
+// normalize removes differences that don't matter, such as line endings and
+// trailing blank lines
+func normalize(apl string) string {
+	apl = strings.ReplaceAll(apl, "\r\n", "\n")
+	return strings.TrimRight(apl, " \t\n")
+}
@@ -0,0 +1,289 @@
+package querysync_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom/axiomtest"
+	"github.com/roessland/curated-axiom-mcp/pkg/querysync"
+)
+
+const curatedAPL = `['events'] | limit 10
+
+// CuratedAxiomMCP:
+//   ToolName: recent_events
+//   Description: Get recent events`
+
+func TestPullWritesCuratedQueries(t *testing.T) {
+	srv := axiomtest.NewServer(t)
+	if err := srv.LoadFixtures(axiomtest.DefaultFixtures()); err != nil {
+		t.Fatalf("Failed to load fixtures: %v", err)
+	}
+	dir := t.TempDir()
+
+	changes, err := querysync.Pull(context.Background(), srv.Client(), dir, querysync.Options{})
+	if err != nil {
+		t.Fatalf("Failed to pull: %v", err)
+	}
+	// Only "Entity data" is curated
+	if len(changes) != 1 {
+		t.Fatalf("Expected 1 change, got %+v", changes)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "entity_data.apl"))
+	if err != nil {
+		t.Fatalf("Expected file named after the tool: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "// starred-query: Entity data\ndeclare query_parameters") {
+		t.Errorf("Unexpected file content:\n%s", data)
+	}
+
+	// A second pull finds nothing to change
+	changes, err = querysync.DiffDir(context.Background(), srv.Client(), dir)
+	if err != nil {
+		t.Fatalf("Failed to diff: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("Expected no drift after pull, got %+v", changes)
+	}
+}
+
+func TestPullPrune(t *testing.T) {
+	srv := axiomtest.NewServer(t)
+	dir := t.TempDir()
+	stale := filepath.Join(dir, "stale.apl")
+	if err := os.WriteFile(stale, querysync.Encode("Stale", curatedAPL), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := querysync.Pull(context.Background(), srv.Client(), dir, querysync.Options{}); err != nil {
+		t.Fatalf("Failed to pull: %v", err)
+	}
+	if _, err := os.Stat(stale); err != nil {
+		t.Errorf("Expected file to be kept without prune: %v", err)
+	}
+
+	if _, err := querysync.Pull(context.Background(), srv.Client(), dir, querysync.Options{Prune: true}); err != nil {
+		t.Fatalf("Failed to pull: %v", err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("Expected file to be removed with prune, got %v", err)
+	}
+}
+
+func TestPush(t *testing.T) {
+	srv := axiomtest.NewServer(t)
+	srv.AddStarredQuery("Changed", curatedAPL)
+	srv.AddStarredQuery("Removed", curatedAPL)
+	srv.AddStarredQuery("Not curated", "['events']")
+
+	dir := t.TempDir()
+	changedAPL := strings.Replace(curatedAPL, "limit 10", "limit 20", 1)
+	files := map[string][]byte{
+		"changed.apl": querysync.Encode("Changed", changedAPL),
+		"new.apl":     querysync.Encode("New", curatedAPL),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Dry run changes nothing
+	changes, err := querysync.Push(context.Background(), srv.Client(), dir, querysync.Options{Prune: true, DryRun: true})
+	if err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+	if len(changes) != 3 || len(srv.StarredQueries()) != 3 {
+		t.Fatalf("Expected 3 planned changes and no applied changes, got %+v", changes)
+	}
+
+	if _, err := querysync.Push(context.Background(), srv.Client(), dir, querysync.Options{Prune: true}); err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, sq := range srv.StarredQueries() {
+		got[sq.Name] = sq.Query.APL
+		// The dataset is taken from the APL of pushed queries
+		if sq.Name != "Not curated" && sq.Dataset != "events" {
+			t.Errorf("Expected %q to have dataset events, got %q", sq.Name, sq.Dataset)
+		}
+	}
+	want := map[string]string{
+		"Changed":     changedAPL,
+		"New":         curatedAPL,
+		"Not curated": "['events']",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected starred queries %v, got %v", want, got)
+	}
+	for name, apl := range want {
+		if got[name] != apl {
+			t.Errorf("Expected %q to have APL %q, got %q", name, apl, got[name])
+		}
+	}
+}
+
+func TestDiffDirDetectsDrift(t *testing.T) {
+	srv := axiomtest.NewServer(t)
+	srv.AddStarredQuery("Changed", curatedAPL)
+
+	dir := t.TempDir()
+	changedAPL := strings.Replace(curatedAPL, "limit 10", "limit 20", 1)
+	if err := os.WriteFile(filepath.Join(dir, "recent_events.apl"), querysync.Encode("Changed", changedAPL), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := querysync.DiffDir(context.Background(), srv.Client(), dir)
+	if err != nil {
+		t.Fatalf("Failed to diff: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Kind != querysync.Update {
+		t.Fatalf("Expected a single update, got %+v", changes)
+	}
+}
+
+func TestDecodeWithoutHeader(t *testing.T) {
+	name, apl := querysync.Decode("queries/errors.apl", []byte("['logs']\r\n\r\n"))
+	if name != "errors" || apl != "['logs']" {
+		t.Errorf("Expected errors and ['logs'], got %q and %q", name, apl)
+	}
+}
+
+func TestFileName(t *testing.T) {
+	if got := querysync.FileName("Entity data", curatedAPL); got != "recent_events.apl" {
+		t.Errorf("Expected tool name as file name, got %s", got)
+	}
+	if got := querysync.FileName("Errors / last hour", "['logs']"); got != "Errors_last_hour.apl" {
+		t.Errorf("Expected file name from query name, got %s", got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	got := querysync.Diff("a\nb\nc", "a\nx\nc")
+	want := "  a\n- b\n+ x\n  c\n"
+	if got != want {
+		t.Errorf("Expected diff %q, got %q", want, got)
+	}
+}
+
+func TestPushTwiceConverges(t *testing.T) {
+	srv := axiomtest.NewServer(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "new.apl"), querysync.Encode("New", curatedAPL), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := querysync.Push(context.Background(), srv.Client(), dir, querysync.Options{}); err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+	changes, err := querysync.Push(context.Background(), srv.Client(), dir, querysync.Options{})
+	if err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+	if len(changes) != 0 || len(srv.StarredQueries()) != 1 {
+		t.Errorf("Expected no changes on the second push, got %+v and %d starred queries", changes, len(srv.StarredQueries()))
+	}
+}
+
+func TestPushRejectsUncuratedQueries(t *testing.T) {
+	tests := map[string]struct {
+		file    []byte
+		wantErr string
+	}{
+		"file without marker": {
+			file:    querysync.Encode("Plain", "['events'] | limit 10"),
+			wantErr: "has no CuratedAxiomMCP metadata, add it or remove the file",
+		},
+		"name of uncurated starred query": {
+			file:    querysync.Encode("Not curated", curatedAPL),
+			wantErr: `is named after starred query "Not curated", which has no CuratedAxiomMCP metadata`,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			srv := axiomtest.NewServer(t)
+			srv.AddStarredQuery("Not curated", "['events']")
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "query.apl"), tt.file, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := querysync.Push(context.Background(), srv.Client(), dir, querysync.Options{})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error %q, got %v", tt.wantErr, err)
+			}
+			if len(srv.StarredQueries()) != 1 {
+				t.Errorf("Expected no starred query to be created, got %+v", srv.StarredQueries())
+			}
+		})
+	}
+}
+
+func TestPullRejectsPathCollisions(t *testing.T) {
+	otherAPL := strings.Replace(curatedAPL, "limit 10", "limit 20", 1)
+	tests := map[string]struct {
+		remote  []string
+		local   []byte
+		wantErr string
+	}{
+		"same tool name": {
+			remote:  []string{"First", "Second"},
+			wantErr: `starred queries "First" and "Second" are both written to`,
+		},
+		"existing file of another query": {
+			remote:  []string{"Second"},
+			local:   querysync.Encode("First", otherAPL),
+			wantErr: `starred queries "First" and "Second" are both written to`,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			srv := axiomtest.NewServer(t)
+			for _, name := range tt.remote {
+				srv.AddStarredQuery(name, curatedAPL)
+			}
+			dir := t.TempDir()
+			path := filepath.Join(dir, "recent_events.apl")
+			if tt.local != nil {
+				if err := os.WriteFile(path, tt.local, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err := querysync.Pull(context.Background(), srv.Client(), dir, querysync.Options{})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Expected error %q, got %v", tt.wantErr, err)
+			}
+			data, _ := os.ReadFile(path)
+			if tt.local != nil && string(data) != string(tt.local) {
+				t.Errorf("Expected %s to be unchanged, got:\n%s", path, data)
+			}
+			if tt.local == nil && data != nil {
+				t.Errorf("Expected nothing to be written, got:\n%s", data)
+			}
+		})
+	}
+}
+
+func TestPullPruneReusesPath(t *testing.T) {
+	srv := axiomtest.NewServer(t)
+	srv.AddStarredQuery("Renamed", curatedAPL)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "recent_events.apl")
+	if err := os.WriteFile(path, querysync.Encode("Original", curatedAPL), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := querysync.Pull(context.Background(), srv.Client(), dir, querysync.Options{Prune: true}); err != nil {
+		t.Fatalf("Failed to pull: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.HasPrefix(string(data), "// starred-query: Renamed\n") {
+		t.Errorf("Expected %s to hold the renamed query, got %q, %v", path, data, err)
+	}
+}
@@ -0,0 +1,156 @@
+package querysync
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/roessland/curated-axiom-mcp/pkg/axiom"
+	"github.com/roessland/curated-axiom-mcp/pkg/caxiom"
+)
+
+// Client is the part of the Axiom client used to sync starred queries
+type Client interface {
+	StarredQueries(ctx context.Context) ([]axiom.StarredQuery, error)
+	CreateStarredQuery(ctx context.Context, sq axiom.StarredQuery) (*axiom.StarredQuery, error)
+	UpdateStarredQuery(ctx context.Context, sq axiom.StarredQuery) (*axiom.StarredQuery, error)
+	DeleteStarredQuery(ctx context.Context, id string) error
+}
+
+// Options control what a sync changes
+type Options struct {
+	Prune  bool // Delete queries missing on the other side
+	DryRun bool // Only report the changes
+}
+
+// DiffDir returns the changes needed to make Axiom match dir
+func DiffDir(ctx context.Context, client Client, dir string) ([]Change, error) {
+	local, err := ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := client.StarredQueries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Plan(local, remote)
+}
+
+// Pull writes the curated starred queries in Axiom to dir. Files of queries
+// that no longer exist in Axiom are removed when pruning. The returned changes
+// are relative to Axiom, as returned by DiffDir before the pull, and the Local
+// file of a Delete change is the file the pull creates. Nothing is written if
+// two queries would be written to the same file.
+func Pull(ctx context.Context, client Client, dir string, options Options) ([]Change, error) {
+	local, err := ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := client.StarredQueries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := Plan(local, remote)
+	if err != nil {
+		return nil, err
+	}
+
+	// Queries of the files that are left after pruning, by path
+	owners := make(map[string]string, len(local))
+	removed := make(map[string]bool)
+	for _, change := range changes {
+		if change.Kind == Create && options.Prune {
+			removed[change.Local.Path] = true
+		}
+	}
+	for _, file := range local {
+		if !removed[file.Path] {
+			owners[file.Path] = file.Name
+		}
+	}
+	for i, change := range changes {
+		if change.Kind != Delete {
+			continue
+		}
+		path := filepath.Join(dir, FileName(change.Name, change.Remote.Query.APL))
+		if owner, ok := owners[path]; ok {
+			return nil, fmt.Errorf("starred queries %q and %q are both written to %s, give one of them a different ToolName", owner, change.Name, path)
+		}
+		owners[path] = change.Name
+		changes[i].Local = &File{Name: change.Name, APL: normalize(change.Remote.Query.APL), Path: path}
+	}
+	if options.DryRun {
+		return changes, nil
+	}
+
+	// Remove files before writing, so a new file may take the path of a
+	// removed one
+	for path := range removed {
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove query file: %w", err)
+		}
+	}
+	for _, change := range changes {
+		switch change.Kind {
+		case Update:
+			// Keep the file name, even if the tool name changed
+			if err := os.WriteFile(change.Local.Path, Encode(change.Name, change.Remote.Query.APL), 0644); err != nil {
+				return nil, fmt.Errorf("failed to write query file: %w", err)
+			}
+		case Delete:
+			if _, err := WriteFile(dir, *change.Remote); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return changes, nil
+}
+
+// Push makes the curated starred queries in Axiom match dir. Starred queries
+// without a file are only deleted when pruning.
+func Push(ctx context.Context, client Client, dir string, options Options) ([]Change, error) {
+	changes, err := DiffDir(ctx, client, dir)
+	if err != nil {
+		return nil, err
+	}
+	if options.DryRun {
+		return changes, nil
+	}
+
+	for _, change := range changes {
+		switch change.Kind {
+		case Create:
+			sq := axiom.StarredQuery{
+				Name:    change.Name,
+				Kind:    "apl",
+				Dataset: caxiom.QueryDataset(change.Local.APL),
+				Query:   axiom.StarredQueryContent{APL: change.Local.APL},
+			}
+			if _, err := client.CreateStarredQuery(ctx, sq); err != nil {
+				return nil, err
+			}
+		case Update:
+			// Keep the metadata of the starred query, and the dataset unless
+			// the APL names another one
+			sq := *change.Remote
+			sq.Query.APL = change.Local.APL
+			if dataset := caxiom.QueryDataset(change.Local.APL); dataset != "" {
+				sq.Dataset = dataset
+			}
+			if _, err := client.UpdateStarredQuery(ctx, sq); err != nil {
+				return nil, err
+			}
+		case Delete:
+			if !options.Prune {
+				continue
+			}
+			if err := client.DeleteStarredQuery(ctx, change.Remote.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return changes, nil
+}
+
+var _ Client = (*axiom.Client)(nil)
@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom"
+)
+
+// aplPositionRegexes match the position of an APL syntax error in Axiom error
+// messages, e.g. "line 3, column 7", "line: 3 col: 7" or "at 3:7"
+var aplPositionRegexes = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)line\s*:?\s*(\d+)\s*,?\s*col(?:umn)?\s*:?\s*(\d+)`),
+	regexp.MustCompile(`(?i)\bat\s+(\d+):(\d+)\b`),
+}
+
+// ClassifyAxiomError converts an error from querying Axiom into an AxiomError
+// with a kind the LLM can act on. It returns nil for a nil error.
+func ClassifyAxiomError(err error) *AxiomError {
+	if err == nil {
+		return nil
+	}
+
+	var axiomErr *AxiomError
+	if errors.As(err, &axiomErr) {
+		return axiomErr
+	}
+
+	classified := &AxiomError{
+		Kind:    AxiomErrorUnknown,
+		Message: err.Error(),
+		Err:     err,
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		classified.Kind = AxiomErrorTimeout
+		return classified
+	}
+
+	var limitErr axiom.LimitError
+	if errors.As(err, &limitErr) {
+		classified.Kind = AxiomErrorRateLimited
+		classified.StatusCode = limitErr.Status
+		classified.Message = limitErr.Message
+		if !limitErr.Limit.Reset.IsZero() {
+			classified.RetryAfter = max(time.Until(limitErr.Limit.Reset).Round(time.Second), time.Second)
+		}
+		return classified
+	}
+
+	var httpErr axiom.HTTPError
+	if !errors.As(err, &httpErr) {
+		return classified
+	}
+	classified.StatusCode = httpErr.Status
+	classified.Message = httpErr.Message
+
+	switch {
+	case httpErr.Status == http.StatusUnauthorized:
+		classified.Kind = AxiomErrorAuth
+	case httpErr.Status == http.StatusForbidden:
+		classified.Kind = AxiomErrorForbidden
+	case httpErr.Status == http.StatusNotFound && strings.Contains(strings.ToLower(httpErr.Message), "dataset"):
+		classified.Kind = AxiomErrorForbidden
+	case httpErr.Status == http.StatusTooManyRequests:
+		classified.Kind = AxiomErrorRateLimited
+	case httpErr.Status == http.StatusRequestTimeout || httpErr.Status == http.StatusGatewayTimeout:
+		classified.Kind = AxiomErrorTimeout
+	case httpErr.Status >= http.StatusInternalServerError:
+		classified.Kind = AxiomErrorServer
+	case httpErr.Status == http.StatusBadRequest || httpErr.Status == http.StatusUnprocessableEntity:
+		classified.Kind = AxiomErrorSyntax
+		classified.Line, classified.Column = aplErrorPosition(httpErr.Message)
+	}
+
+	return classified
+}
+
+// aplErrorPosition extracts the line and column of an APL syntax error
+func aplErrorPosition(message string) (line, column int) {
+	for _, re := range aplPositionRegexes {
+		if match := re.FindStringSubmatch(message); match != nil {
+			line, _ = strconv.Atoi(match[1])
+			column, _ = strconv.Atoi(match[2])
+			return line, column
+		}
+	}
+	return 0, 0
+}
+
+// Hint returns a concrete suggestion for how to recover from the error
+func (e *AxiomError) Hint() string {
+	switch e.Kind {
+	case AxiomErrorAuth:
+		return "The Axiom token is missing, invalid or expired. This cannot be fixed by changing the query; ask the user to check AXIOM_TOKEN."
+	case AxiomErrorForbidden:
+		return "The token cannot read the dataset, or the dataset does not exist. Check the dataset name for typos, or use a dataset the token has access to."
+	case AxiomErrorSyntax:
+		if e.Line > 0 {
+			return fmt.Sprintf("Fix the APL near line %d, column %d and run the query again.", e.Line, e.Column)
+		}
+		return "Fix the APL syntax error described in the message and run the query again."
+	case AxiomErrorRateLimited:
+		if e.RetryAfter > 0 {
+			return fmt.Sprintf("Axiom is rate limiting queries. Wait %.0f s before retrying, and avoid running many queries in parallel.", e.RetryAfter.Seconds())
+		}
+		return "Axiom is rate limiting queries. Wait before retrying, and avoid running many queries in parallel."
+	case AxiomErrorTimeout:
+		return "The query took too long. Narrow the time range, add filters, or aggregate before returning rows."
+	case AxiomErrorServer:
+		return "Axiom failed to process the request and retries did not help. Try again later."
+	default:
+		return "Check the error message and the query, then try again."
+	}
+}
@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/axiomhq/axiom-go/axiom"
+)
+
+func TestClassifyAxiomError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		kind   AxiomErrorKind
+		line   int
+		column int
+	}{
+		{
+			name: "unauthorized",
+			err:  axiom.HTTPError{Status: http.StatusUnauthorized, Message: "invalid token"},
+			kind: AxiomErrorAuth,
+		},
+		{
+			name: "forbidden",
+			err:  axiom.HTTPError{Status: http.StatusForbidden, Message: "forbidden"},
+			kind: AxiomErrorForbidden,
+		},
+		{
+			name: "dataset not found",
+			err:  axiom.HTTPError{Status: http.StatusNotFound, Message: "dataset 'logz' not found"},
+			kind: AxiomErrorForbidden,
+		},
+		{
+			name:   "syntax error with position",
+			err:    axiom.HTTPError{Status: http.StatusBadRequest, Message: "unexpected token at line 3, column 7"},
+			kind:   AxiomErrorSyntax,
+			line:   3,
+			column: 7,
+		},
+		{
+			name:   "syntax error with short position",
+			err:    axiom.HTTPError{Status: http.StatusBadRequest, Message: "parse error at 2:14: expected expression"},
+			kind:   AxiomErrorSyntax,
+			line:   2,
+			column: 14,
+		},
+		{
+			name: "syntax error without position",
+			err:  axiom.HTTPError{Status: http.StatusBadRequest, Message: "unknown function"},
+			kind: AxiomErrorSyntax,
+		},
+		{
+			name: "rate limited",
+			err:  axiom.HTTPError{Status: http.StatusTooManyRequests},
+			kind: AxiomErrorRateLimited,
+		},
+		{
+			name: "server error",
+			err:  axiom.HTTPError{Status: http.StatusBadGateway},
+			kind: AxiomErrorServer,
+		},
+		{
+			name: "gateway timeout",
+			err:  axiom.HTTPError{Status: http.StatusGatewayTimeout},
+			kind: AxiomErrorTimeout,
+		},
+		{
+			name: "deadline exceeded",
+			err:  fmt.Errorf("query execution failed: %w", context.DeadlineExceeded),
+			kind: AxiomErrorTimeout,
+		},
+		{
+			name: "wrapped http error",
+			err:  fmt.Errorf("query execution failed: %w", axiom.HTTPError{Status: http.StatusUnauthorized}),
+			kind: AxiomErrorAuth,
+		},
+		{
+			name: "unknown",
+			err:  errors.New("connection refused"),
+			kind: AxiomErrorUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			axiomErr := ClassifyAxiomError(tt.err)
+			if axiomErr.Kind != tt.kind {
+				t.Errorf("Expected kind %s, got %s", tt.kind, axiomErr.Kind)
+			}
+			if axiomErr.Line != tt.line || axiomErr.Column != tt.column {
+				t.Errorf("Expected position %d:%d, got %d:%d", tt.line, tt.column, axiomErr.Line, axiomErr.Column)
+			}
+			if axiomErr.Hint() == "" {
+				t.Error("Expected a hint")
+			}
+			if !errors.Is(axiomErr, tt.err) {
+				t.Error("Expected classified error to wrap the original error")
+			}
+		})
+	}
+}
+
+func TestClassifyAxiomErrorLimit(t *testing.T) {
+	err := fmt.Errorf("query execution failed: %w", axiom.LimitError{
+		HTTPError: axiom.HTTPError{Status: http.StatusTooManyRequests, Message: "query limit exceeded"},
+		Limit:     axiom.Limit{Reset: time.Now().Add(30 * time.Second)},
+	})
+
+	axiomErr := ClassifyAxiomError(err)
+
+	if axiomErr.Kind != AxiomErrorRateLimited {
+		t.Errorf("Expected kind %s, got %s", AxiomErrorRateLimited, axiomErr.Kind)
+	}
+	if axiomErr.RetryAfter < 29*time.Second || axiomErr.RetryAfter > 30*time.Second {
+		t.Errorf("Expected retry after about 30s, got %v", axiomErr.RetryAfter)
+	}
+}
+
+func TestClassifyAxiomErrorNil(t *testing.T) {
+	if ClassifyAxiomError(nil) != nil {
+		t.Error("Expected nil for nil error")
+	}
+}
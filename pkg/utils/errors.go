@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"time"
 )
 
 // QueryNotFoundError represents an error when a query is not found
@@ -23,16 +24,38 @@ func (e *ParameterError) Error() string {
 	return fmt.Sprintf("parameter '%s': %s", e.Parameter, e.Message)
 }
 
+// AxiomErrorKind classifies errors from the Axiom API
+type AxiomErrorKind string
+
+const (
+	AxiomErrorAuth        AxiomErrorKind = "auth"              // Token is missing, invalid or expired
+	AxiomErrorForbidden   AxiomErrorKind = "forbidden_dataset" // Token may not access the dataset, or it doesn't exist
+	AxiomErrorSyntax      AxiomErrorKind = "apl_syntax"        // The APL query is invalid
+	AxiomErrorRateLimited AxiomErrorKind = "rate_limited"      // Query or request limit reached
+	AxiomErrorTimeout     AxiomErrorKind = "timeout"           // Query took too long
+	AxiomErrorServer      AxiomErrorKind = "server_error"      // Axiom failed to process the request
+	AxiomErrorUnknown     AxiomErrorKind = "unknown"
+)
+
 // AxiomError represents an error from the Axiom API
 type AxiomError struct {
 	StatusCode int
 	Message    string
+	Kind       AxiomErrorKind
+	Line       int           // Line of an APL syntax error, zero if unknown
+	Column     int           // Column of an APL syntax error, zero if unknown
+	RetryAfter time.Duration // How long to wait before retrying a rate limited query
+	Err        error         // The underlying error
 }
 
 func (e *AxiomError) Error() string {
 	return fmt.Sprintf("axiom error (status %d): %s", e.StatusCode, e.Message)
 }
 
+func (e *AxiomError) Unwrap() error {
+	return e.Err
+}
+
 // ConfigError represents a configuration error
 type ConfigError struct {
 	Field   string